@@ -11,6 +11,7 @@ require (
 	github.com/go-logr/zapr v1.2.3
 	github.com/jarcoal/httpmock v1.3.0
 	github.com/kr/pretty v0.1.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -0,0 +1,159 @@
+package util
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileReloader caches a value loaded from a set of files and loads it again
+// as soon as one of the files changes.
+type FileReloader[T any] struct {
+	mu     sync.Mutex
+	paths  []string
+	load   func() (T, error)
+	stamps []fileStamp
+	value  T
+}
+
+// fileStamp identifies the version of a file.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewFileReloader creates a reloader and loads the initial value, which must succeed.
+func NewFileReloader[T any](load func() (T, error), paths ...string) (*FileReloader[T], error) {
+	r := &FileReloader[T]{paths: paths, load: load}
+	stamps, err := r.stat()
+	if err != nil {
+		return nil, err
+	}
+	v, err := load()
+	if err != nil {
+		return nil, err
+	}
+	r.stamps, r.value = stamps, v
+	return r, nil
+}
+
+// Get returns the current value, reloading it if any of the files changed.
+// If the files cannot be loaded, e.g. because they are being rewritten, the
+// previous value is kept and the reload is attempted again on the next call.
+func (r *FileReloader[T]) Get() T {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stamps, err := r.stat()
+	if err != nil || !r.changed(stamps) {
+		return r.value
+	}
+	v, err := r.load()
+	if err != nil {
+		return r.value
+	}
+	r.stamps, r.value = stamps, v
+	return r.value
+}
+
+func (r *FileReloader[T]) stat() ([]fileStamp, error) {
+	stamps := make([]fileStamp, len(r.paths))
+	for i, p := range r.paths {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		stamps[i] = fileStamp{modTime: fi.ModTime(), size: fi.Size()}
+	}
+	return stamps, nil
+}
+
+func (r *FileReloader[T]) changed(stamps []fileStamp) bool {
+	for i := range stamps {
+		if !stamps[i].modTime.Equal(r.stamps[i].modTime) || stamps[i].size != r.stamps[i].size {
+			return true
+		}
+	}
+	return false
+}
+
+// enableReload replaces the static CA pool and client certificate of the TLS config
+// with callbacks, which reload the files whenever they change. Certificates given
+// as PEM are static and thus not reloaded. If the CA is reloaded, the returned function
+// creates the connection verifier for a server name, and is nil otherwise.
+func enableReload(cfg *tls.Config, opts TLSOptions, verifyPins func(tls.ConnectionState) error) (func(string) func(tls.ConnectionState) error, error) {
+	var verifierFor func(string) func(tls.ConnectionState) error
+	if opts.CAPath != "" && !opts.InsecureSkipVerify {
+		pools, err := NewFileReloader(func() (*x509.CertPool, error) {
+			return loadCertPool(opts.CAPath)
+		}, opts.CAPath)
+		if err != nil {
+			return nil, err
+		}
+		verifierFor = func(serverName string) func(tls.ConnectionState) error {
+			verifyChain := verifyChain(pools, serverName)
+			return func(cs tls.ConnectionState) error {
+				if err := verifyChain(cs); err != nil {
+					return err
+				}
+				if verifyPins != nil {
+					return verifyPins(cs)
+				}
+				return nil
+			}
+		}
+		// The chain is verified by hand against the current pool, so the
+		// built-in verification with the static RootCAs is disabled.
+		cfg.RootCAs = nil
+		cfg.InsecureSkipVerify = true //nolint:gosec
+		cfg.VerifyConnection = verifierFor(opts.ServerName)
+	}
+
+	if opts.ClientCertPath != "" && opts.ClientKeyPath != "" && opts.ClientCertPEM == nil && opts.ClientKeyPEM == nil {
+		certs, err := NewFileReloader(func() (*tls.Certificate, error) {
+			return loadClientCert(opts)
+		}, opts.ClientCertPath, opts.ClientKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = nil
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return certs.Get(), nil
+		}
+	}
+	return verifierFor, nil
+}
+
+// verifyChain returns a connection verifier which verifies the server certificate chain
+// against the current CA pool, like the built-in verification of crypto/tls does.
+func verifyChain(pools *FileReloader[*x509.CertPool], serverName string) func(tls.ConnectionState) error {
+	return func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			return errors.New("server presented no certificate")
+		}
+		name := serverName
+		if name == "" {
+			name = cs.ServerName
+		}
+		if name == "" {
+			return errors.New("no server name to verify the certificate against")
+		}
+
+		opts := x509.VerifyOptions{
+			Roots:         pools.Get(),
+			DNSName:       name,
+			Intermediates: x509.NewCertPool(),
+		}
+		for _, c := range cs.PeerCertificates[1:] {
+			opts.Intermediates.AddCert(c)
+		}
+		if _, err := cs.PeerCertificates[0].Verify(opts); err != nil {
+			return fmt.Errorf("failed to verify server certificate: %w", err)
+		}
+		return nil
+	}
+}
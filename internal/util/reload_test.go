@@ -0,0 +1,145 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewTLSConfig_ReloadCA(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	otherCA, _ := testCertificate(t, "other-ca")
+	srvCA := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	caPath := filepath.Join(t.TempDir(), "ca.crt")
+	writeFile(t, caPath, otherCA)
+
+	cfg, err := NewTLSConfig(TLSOptions{CAPath: caPath, ServerName: "example.com", Reload: true})
+	if err != nil {
+		t.Fatalf("NewTLSConfig() error = %v", err)
+	}
+	get := func() error {
+		c := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg, DisableKeepAlives: true}}
+		resp, err := c.Get(srv.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	if err := get(); err == nil {
+		t.Fatalf("Get() expected error with unknown CA")
+	}
+	rewriteFile(t, caPath, srvCA)
+	if err := get(); err != nil {
+		t.Fatalf("Get() error = %v after CA was replaced", err)
+	}
+}
+
+func TestNewTLSConfig_ReloadClientCert(t *testing.T) {
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "client.crt")
+	keyPath := filepath.Join(dir, "client.key")
+	oldCert, oldKey := testCertificate(t, "old")
+	writeFile(t, certPath, oldCert)
+	writeFile(t, keyPath, oldKey)
+
+	cfg, err := NewTLSConfig(TLSOptions{ClientCertPath: certPath, ClientKeyPath: keyPath, Reload: true})
+	if err != nil {
+		t.Fatalf("NewTLSConfig() error = %v", err)
+	}
+	if cfg.GetClientCertificate == nil {
+		t.Fatalf("NewTLSConfig() did not set GetClientCertificate")
+	}
+	assertClientCert(t, cfg, oldCert)
+
+	// a half written certificate keeps the previous one
+	rewriteFile(t, certPath, []byte("garbage"))
+	assertClientCert(t, cfg, oldCert)
+
+	newCert, newKey := testCertificate(t, "new")
+	rewriteFile(t, certPath, newCert)
+	rewriteFile(t, keyPath, newKey)
+	assertClientCert(t, cfg, newCert)
+}
+
+func assertClientCert(t *testing.T, cfg *tls.Config, wantPEM []byte) {
+	t.Helper()
+	got, err := cfg.GetClientCertificate(&tls.CertificateRequestInfo{})
+	if err != nil {
+		t.Fatalf("GetClientCertificate() error = %v", err)
+	}
+	block, _ := pem.Decode(wantPEM)
+	if !bytes.Equal(got.Certificate[0], block.Bytes) {
+		t.Errorf("GetClientCertificate() returned an unexpected certificate")
+	}
+}
+
+// rewriteFile replaces the file content and moves its modification time forward,
+// so the change is detected even on file systems with a coarse time resolution.
+func rewriteFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("failed to stat %s: %v", path, err)
+	}
+	writeFile(t, path, data)
+	mtime := fi.ModTime().Add(time.Second)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatalf("failed to change times of %s: %v", path, err)
+	}
+}
+
+func TestNewTLSDialer_ReloadIP(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	otherCA, _ := testCertificate(t, "other-ca")
+	srvCA := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	caPath := filepath.Join(t.TempDir(), "ca.crt")
+	writeFile(t, caPath, otherCA)
+
+	// the server is dialed by IP, which the certificate has as IP SAN
+	opts := TLSOptions{CAPath: caPath, Reload: true}
+	dial, err := NewTLSDialer(opts, []string{"http/1.1"}, (&net.Dialer{}).DialContext)
+	if err != nil {
+		t.Fatalf("NewTLSDialer() error = %v", err)
+	}
+	get := func() error {
+		c := &http.Client{Transport: &http.Transport{DialTLSContext: dial, DisableKeepAlives: true}}
+		resp, err := c.Get(srv.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	if err := get(); err == nil {
+		t.Fatalf("Get() expected error with unknown CA")
+	}
+	rewriteFile(t, caPath, srvCA)
+	if err := get(); err != nil {
+		t.Fatalf("Get() error = %v after CA was replaced", err)
+	}
+
+	// the certificate is still verified against the dialed IP
+	toServer := func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, srv.Listener.Addr().String())
+	}
+	dialOther, err := NewTLSDialer(opts, nil, toServer)
+	if err != nil {
+		t.Fatalf("NewTLSDialer() error = %v", err)
+	}
+	if _, err := dialOther(context.Background(), "tcp", "127.0.0.2:443"); err == nil {
+		t.Errorf("dial() expected error for an IP which is not in the certificate")
+	}
+}
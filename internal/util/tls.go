@@ -1,38 +1,211 @@
 package util
 
 import (
+	"context"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"net"
 	"os"
+	"strings"
 )
 
-// NewTLSConfig creates a new TLS config. If the cert path is empty,
-// the system pool is returned with insecureSkipVerify set to true.
-func NewTLSConfig(certPath string) *tls.Config {
-	pool := loadCertPool(certPath)
-	return &tls.Config{
+// TLSOptions holds the certificates used to set up a TLS connection.
+type TLSOptions struct {
+	// CAPath is the path to the CA certificate used to verify the server.
+	// If empty, the system pool is used.
+	CAPath string
+	// ClientCertPath and ClientKeyPath are the paths to the client certificate and key.
+	ClientCertPath string
+	ClientKeyPath  string
+	// ClientCertPEM and ClientKeyPEM are the PEM encoded client certificate and key.
+	// They take precedence over ClientCertPath and ClientKeyPath.
+	ClientCertPEM []byte
+	ClientKeyPEM  []byte
+	// InsecureSkipVerify disables the verification of the server certificate chain and host name.
+	InsecureSkipVerify bool
+	// ServerName overrides the name the server certificate is verified against.
+	ServerName string
+	// PinnedFingerprints are the hex encoded SHA-256 fingerprints of the accepted server certificates.
+	// If set, the server certificate must match one of them, even if InsecureSkipVerify is set.
+	PinnedFingerprints []string
+	// Reload enables reloading the CA and client certificate files whenever they change,
+	// without creating a new TLS config.
+	Reload bool
+}
+
+// NewTLSConfig creates a new TLS config. The server certificate is verified against
+// the CA at CAPath, or the system pool if the path is empty, unless verification is
+// explicitly disabled. A client certificate is added if one is configured.
+// If reloading is enabled, the CA and client certificate files are read again
+// for every new connection after they changed.
+func NewTLSConfig(opts TLSOptions) (*tls.Config, error) {
+	cfg, _, err := newTLSConfig(opts)
+	return cfg, err
+}
+
+// DialFunc dials a network connection, like net.Dialer.DialContext.
+type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// NewTLSDialer returns a function dialing TLS connections with the TLS config of the options,
+// offering the given application protocols, e.g. for http.Transport.DialTLSContext.
+// Unlike the TLS config alone, it knows the dialed host. Without a server name, the certificate
+// verification of a reloaded CA thus checks the host, which may be an IP address, like the
+// built-in verification does.
+func NewTLSDialer(opts TLSOptions, nextProtos []string, dial DialFunc) (DialFunc, error) {
+	cfg, verifierFor, err := newTLSConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	cfg.NextProtos = nextProtos
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		c := cfg.Clone()
+		if c.ServerName == "" {
+			c.ServerName = host
+			if verifierFor != nil {
+				c.VerifyConnection = verifierFor(host)
+			}
+		}
+		tlsConn := tls.Client(conn, c)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return tlsConn, nil
+	}, nil
+}
+
+// newTLSConfig creates a new TLS config. If the CA is reloaded, the connection verifier
+// for a server name is returned as well.
+func newTLSConfig(opts TLSOptions) (*tls.Config, func(string) func(tls.ConnectionState) error, error) {
+	pool, err := loadCertPool(opts.CAPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
 		RootCAs:            pool,
-		InsecureSkipVerify: certPath == "", //nolint:gosec
+		ServerName:         opts.ServerName,
+		InsecureSkipVerify: opts.InsecureSkipVerify, //nolint:gosec
+	}
+
+	cert, err := loadClientCert(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cert != nil {
+		cfg.Certificates = []tls.Certificate{*cert}
+	}
+
+	if len(opts.PinnedFingerprints) > 0 {
+		pins, err := parseFingerprints(opts.PinnedFingerprints)
+		if err != nil {
+			return nil, nil, err
+		}
+		cfg.VerifyConnection = verifyPins(pins)
+	}
+
+	if !opts.Reload {
+		return cfg, nil, nil
+	}
+	verifierFor, err := enableReload(cfg, opts, cfg.VerifyConnection)
+	if err != nil {
+		return nil, nil, err
 	}
+	return cfg, verifierFor, nil
 }
 
-// loadCertPool loads a certificate pool from a file. Panics on error.
-func loadCertPool(path string) *x509.CertPool {
+// loadCertPool loads a certificate pool from a file, or the system pool if the path is empty.
+func loadCertPool(path string) (*x509.CertPool, error) {
 	if path == "" {
 		pool, err := x509.SystemCertPool()
 		if err != nil {
-			panic(fmt.Sprintf("failed to load system certificate pool: %v", err))
+			return nil, fmt.Errorf("failed to load system certificate pool: %w", err)
 		}
-		return pool
+		return pool, nil
 	}
 	certPool := x509.NewCertPool()
 	pem, err := os.ReadFile(path)
 	if err != nil {
-		panic(fmt.Sprintf("failed to read cert file: %v", err))
+		return nil, fmt.Errorf("failed to read cert file: %w", err)
 	}
 	if !certPool.AppendCertsFromPEM(pem) {
-		panic("failed to append certificate to pool")
+		return nil, fmt.Errorf("failed to append certificate %s to pool", path)
+	}
+	return certPool, nil
+}
+
+// loadClientCert loads the client certificate, preferring the in-memory PEM over the files.
+// Returns nil if no client certificate is configured.
+func loadClientCert(opts TLSOptions) (*tls.Certificate, error) {
+	certPEM, keyPEM := opts.ClientCertPEM, opts.ClientKeyPEM
+	if certPEM == nil && opts.ClientCertPath != "" {
+		b, err := os.ReadFile(opts.ClientCertPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client cert file: %w", err)
+		}
+		certPEM = b
+	}
+	if keyPEM == nil && opts.ClientKeyPath != "" {
+		b, err := os.ReadFile(opts.ClientKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client key file: %w", err)
+		}
+		keyPEM = b
+	}
+	if certPEM == nil && keyPEM == nil {
+		return nil, nil
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client certificate: %w", err)
 	}
-	return certPool
+	return &cert, nil
+}
+
+// parseFingerprints normalizes the given SHA-256 fingerprints, which may contain colons.
+func parseFingerprints(fingerprints []string) (map[string]struct{}, error) {
+	pins := make(map[string]struct{}, len(fingerprints))
+	for _, f := range fingerprints {
+		n := strings.ToLower(strings.ReplaceAll(f, ":", ""))
+		b, err := hex.DecodeString(n)
+		if err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("invalid SHA-256 certificate fingerprint %q", f)
+		}
+		pins[n] = struct{}{}
+	}
+	return pins, nil
+}
+
+// verifyPins returns a connection verifier which accepts only server
+// certificates matching one of the given fingerprints.
+func verifyPins(pins map[string]struct{}) func(tls.ConnectionState) error {
+	return func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			return errors.New("server presented no certificate")
+		}
+		if _, ok := pins[Fingerprint(cs.PeerCertificates[0])]; !ok {
+			return fmt.Errorf("server certificate %s does not match any pinned fingerprint",
+				cs.PeerCertificates[0].Subject.CommonName)
+		}
+		return nil
+	}
+}
+
+// Fingerprint returns the hex encoded SHA-256 fingerprint of the certificate.
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
 }
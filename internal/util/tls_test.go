@@ -0,0 +1,212 @@
+package util
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewTLSConfig_ClientCert(t *testing.T) {
+	certPEM, keyPEM := testCertificate(t, "icinga-client")
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "client.crt")
+	keyPath := filepath.Join(dir, "client.key")
+	writeFile(t, certPath, certPEM)
+	writeFile(t, keyPath, keyPEM)
+
+	tests := []struct {
+		name      string
+		opts      TLSOptions
+		wantCerts int
+	}{
+		{
+			name:      "no client certificate",
+			opts:      TLSOptions{},
+			wantCerts: 0,
+		},
+		{
+			name:      "client certificate from files",
+			opts:      TLSOptions{ClientCertPath: certPath, ClientKeyPath: keyPath},
+			wantCerts: 1,
+		},
+		{
+			name:      "client certificate from PEM",
+			opts:      TLSOptions{ClientCertPEM: certPEM, ClientKeyPEM: keyPEM},
+			wantCerts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := NewTLSConfig(tt.opts)
+			if err != nil {
+				t.Fatalf("NewTLSConfig() error = %v", err)
+			}
+			if len(cfg.Certificates) != tt.wantCerts {
+				t.Errorf("NewTLSConfig() certificates = %d, want %d", len(cfg.Certificates), tt.wantCerts)
+			}
+		})
+	}
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	certPEM, _ := testCertificate(t, "icinga-master")
+	dir := t.TempDir()
+	caPath := filepath.Join(dir, "ca.crt")
+	invalidPath := filepath.Join(dir, "invalid.crt")
+	writeFile(t, caPath, certPEM)
+	writeFile(t, invalidPath, []byte("not a certificate"))
+
+	tests := []struct {
+		name         string
+		opts         TLSOptions
+		wantInsecure bool
+		wantErr      bool
+	}{
+		{
+			name:         "system pool is verified by default",
+			opts:         TLSOptions{},
+			wantInsecure: false,
+		},
+		{
+			name:         "explicit insecure",
+			opts:         TLSOptions{InsecureSkipVerify: true},
+			wantInsecure: true,
+		},
+		{
+			name: "ca file",
+			opts: TLSOptions{CAPath: caPath, ServerName: "icinga-master"},
+		},
+		{
+			name:    "missing ca file",
+			opts:    TLSOptions{CAPath: filepath.Join(dir, "missing.crt")},
+			wantErr: true,
+		},
+		{
+			name:    "invalid ca file",
+			opts:    TLSOptions{CAPath: invalidPath},
+			wantErr: true,
+		},
+		{
+			name:    "invalid fingerprint",
+			opts:    TLSOptions{PinnedFingerprints: []string{"AB:CD"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := NewTLSConfig(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewTLSConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if cfg.InsecureSkipVerify != tt.wantInsecure {
+				t.Errorf("NewTLSConfig() InsecureSkipVerify = %v, want %v", cfg.InsecureSkipVerify, tt.wantInsecure)
+			}
+			if cfg.ServerName != tt.opts.ServerName {
+				t.Errorf("NewTLSConfig() ServerName = %q, want %q", cfg.ServerName, tt.opts.ServerName)
+			}
+		})
+	}
+}
+
+func TestNewTLSConfig_PinnedFingerprints(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	fp := Fingerprint(srv.Certificate())
+
+	tests := []struct {
+		name    string
+		pins    []string
+		wantErr bool
+	}{
+		{
+			name: "matching fingerprint",
+			pins: []string{fp},
+		},
+		{
+			name: "matching fingerprint with colons",
+			pins: []string{strings.ToUpper(withColons(fp))},
+		},
+		{
+			name:    "no matching fingerprint",
+			pins:    []string{strings.Repeat("0", 64)},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := NewTLSConfig(TLSOptions{InsecureSkipVerify: true, PinnedFingerprints: tt.pins})
+			if err != nil {
+				t.Fatalf("NewTLSConfig() error = %v", err)
+			}
+			c := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+			resp, err := c.Get(srv.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Get() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// withColons inserts a colon between every byte of the hex encoded fingerprint.
+func withColons(fp string) string {
+	var parts []string
+	for i := 0; i < len(fp); i += 2 {
+		parts = append(parts, fp[i:i+2])
+	}
+	return strings.Join(parts, ":")
+}
+
+// testCertificate creates a self-signed certificate with the given common name
+// and returns the PEM encoded certificate and key.
+func testCertificate(t *testing.T, cn string) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed generating key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: cn},
+		DNSNames:              []string{cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed creating certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed marshalling key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("failed writing %s: %v", path, err)
+	}
+}
@@ -3,12 +3,12 @@ package api
 import (
 	"context"
 	"fmt"
-
-	"github.com/go-logr/logr"
 )
 
 type Actions interface {
-	ProcessCheckResult(ctx context.Context, srv *Service) error
+	ProcessCheckResult(ctx context.Context, srv *Service) (ActionResults, error)
+	AcknowledgeProblem(ctx context.Context, req *AcknowledgeProblemRequest) (ActionResults, error)
+	RemoveAcknowledgement(ctx context.Context, req *RemoveAcknowledgementRequest) (ActionResults, error)
 }
 
 // actions implements the Actions interface.
@@ -17,38 +17,153 @@ type actions struct {
 }
 
 // newActionsClient returns a new Actions client.
-func newActionsClient(cfg *Config, log *logr.Logger) Actions {
-	l := log.WithName("actions")
-	return &actions{cs: New(cfg, &l)}
+func newActionsClient(ic *Icinga) Actions {
+	return &actions{cs: ic.named("actions")}
 }
 
-// ProcessCheckResult updates the given services check result in Icinga in the current host.
-func (c *actions) ProcessCheckResult(ctx context.Context, srv *Service) error {
+// ProcessCheckResult updates the check result of the service, selected by its host name and short name.
+// The names are passed as filter variables, so they need no escaping.
+// Every submission advances the check attempt of the service, so the request is only retried
+// if the retry policy allows retrying non-idempotent requests.
+func (c *actions) ProcessCheckResult(ctx context.Context, srv *Service) (ActionResults, error) {
 	if srv == nil {
-		return fmt.Errorf("service cannot be nil")
+		return nil, fmt.Errorf("service cannot be nil")
 	}
 
+	cr := srv.LastCheckResult
 	pu := &UpdateCheckOutputRequest{
 		Type:            "Service",
-		Filter:          fmt.Sprintf("host.name==\"%s\" && types.name==\"%s\"", srv.HostName, srv.Name),
-		ExitStatus:      srv.LastCheckResult.ExitStatus,
-		PluginOutput:    srv.LastCheckResult.Output,
-		PerformanceData: srv.LastCheckResult.PerformanceData,
+		Filter:          "host.name==host_name && service.name==service_name",
+		FilterVars:      map[string]interface{}{"host_name": srv.HostName, "service_name": srv.Name},
+		ExitStatus:      cr.ExitStatus,
+		PluginOutput:    cr.Output,
+		PerformanceData: cr.PerformanceData,
+		CheckSource:     cr.CheckSource,
+		TTL:             cr.TTL,
+	}
+	if !cr.ExecutionStart.IsZero() {
+		pu.ExecutionStart = &cr.ExecutionStart
+	}
+	if !cr.ExecutionEnd.IsZero() {
+		pu.ExecutionEnd = &cr.ExecutionEnd
+	}
+
+	return c.call(ctx, c.cs.Post().Object("process-check-result").Body(pu))
+}
+
+// AcknowledgeProblem acknowledges the problems of all hosts or services matching the request.
+func (c *actions) AcknowledgeProblem(ctx context.Context, req *AcknowledgeProblemRequest) (ActionResults, error) {
+	if req == nil {
+		return nil, fmt.Errorf("request cannot be nil")
+	}
+	if req.Author == "" || req.Comment == "" {
+		return nil, fmt.Errorf("author and comment are required to acknowledge a problem")
+	}
+	return c.call(ctx, c.cs.Post().Object("acknowledge-problem").Body(req))
+}
+
+// RemoveAcknowledgement removes the acknowledgements of all hosts or services matching the request.
+func (c *actions) RemoveAcknowledgement(ctx context.Context, req *RemoveAcknowledgementRequest) (ActionResults, error) {
+	if req == nil {
+		return nil, fmt.Errorf("request cannot be nil")
+	}
+	return c.call(ctx, c.cs.Post().Object("remove-acknowledgement").Body(req).Idempotent())
+}
+
+// call sends the action request and decodes the results of the affected objects.
+// The results are returned even if the action failed for some of the objects.
+func (c *actions) call(ctx context.Context, r *Request) (ActionResults, error) {
+	res := r.Endpoint("actions").Call(ctx)
+	if err := res.Error(); err != nil {
+		results, _ := res.Results()
+		return results, err
 	}
+	return res.Results()
+}
+
+// ActionResults are the results of an action for every affected object.
+type ActionResults []ObjectResult
+
+// Succeeded returns the results of the objects the action succeeded for.
+func (r ActionResults) Succeeded() ActionResults {
+	return r.filter(true)
+}
+
+// Failed returns the results of the objects the action failed for.
+func (r ActionResults) Failed() ActionResults {
+	return r.filter(false)
+}
+
+// ByName returns the result of the object with the given name, e.g. "host!service" for services.
+func (r ActionResults) ByName(name string) (ObjectResult, bool) {
+	for i := range r {
+		if r[i].Name == name {
+			return r[i], true
+		}
+	}
+	return ObjectResult{}, false
+}
 
-	res := c.cs.Post().
-		Endpoint("actions").
-		Object("process-check-result").
-		Body(pu).
-		Call(ctx)
-	return res.Error()
+func (r ActionResults) filter(succeeded bool) ActionResults {
+	var filtered ActionResults
+	for i := range r {
+		if r[i].Succeeded() == succeeded {
+			filtered = append(filtered, r[i])
+		}
+	}
+	return filtered
 }
 
 // UpdateCheckOutputRequest is the request body for updating the check output of a service.
 type UpdateCheckOutputRequest struct {
-	Type            string   `json:"type"`
-	Filter          string   `json:"filter"`
-	ExitStatus      int      `json:"exit_status"`
-	PluginOutput    string   `json:"plugin_output"`
-	PerformanceData []string `json:"performance_data"`
+	Type   string `json:"type"`
+	Filter string `json:"filter"`
+	// FilterVars are the variables referenced in the filter.
+	FilterVars      map[string]interface{} `json:"filter_vars,omitempty"`
+	ExitStatus      int                    `json:"exit_status"`
+	PluginOutput    string                 `json:"plugin_output"`
+	PerformanceData []string               `json:"performance_data"`
+	// ExecutionStart is the time the check started at. Nil uses the time the result was received.
+	ExecutionStart *IcingaTime `json:"execution_start,omitempty"`
+	// ExecutionEnd is the time the check ended at. Nil uses the time the result was received.
+	ExecutionEnd *IcingaTime `json:"execution_end,omitempty"`
+	// CheckSource is the name of the node which executed the check.
+	CheckSource string `json:"check_source,omitempty"`
+	// TTL is the time until the next result is expected. Once it passes, the freshness
+	// check of the service is executed. Zero keeps the freshness threshold of the service.
+	TTL IcingaDuration `json:"ttl,omitempty"`
+}
+
+// AcknowledgeProblemRequest is the request body for acknowledging the problems of hosts or services.
+type AcknowledgeProblemRequest struct {
+	// Type is the type of the objects to acknowledge, "Host" or "Service".
+	Type string `json:"type"`
+	// Filter selects the objects to acknowledge, e.g. `host.name=="web1"`.
+	Filter string `json:"filter,omitempty"`
+	// FilterVars are the variables referenced in the filter.
+	FilterVars map[string]interface{} `json:"filter_vars,omitempty"`
+	// Author is the name of the user acknowledging the problem.
+	Author string `json:"author"`
+	// Comment is the text of the acknowledgement comment.
+	Comment string `json:"comment"`
+	// Expiry is the time the acknowledgement expires at. Nil never expires.
+	Expiry *IcingaTime `json:"expiry,omitempty"`
+	// Sticky keeps the acknowledgement until the object recovers, instead of until the state changes.
+	Sticky bool `json:"sticky,omitempty"`
+	// Notify sends a notification about the acknowledgement.
+	Notify bool `json:"notify,omitempty"`
+	// Persistent keeps the comment after the acknowledgement is removed.
+	Persistent bool `json:"persistent,omitempty"`
+}
+
+// RemoveAcknowledgementRequest is the request body for removing the acknowledgements of hosts or services.
+type RemoveAcknowledgementRequest struct {
+	// Type is the type of the objects, "Host" or "Service".
+	Type string `json:"type"`
+	// Filter selects the objects, e.g. `host.name=="web1"`.
+	Filter string `json:"filter,omitempty"`
+	// FilterVars are the variables referenced in the filter.
+	FilterVars map[string]interface{} `json:"filter_vars,omitempty"`
+	// Author is the name of the user removing the acknowledgement.
+	Author string `json:"author,omitempty"`
 }
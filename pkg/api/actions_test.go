@@ -0,0 +1,223 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+)
+
+func Test_actions_AcknowledgeProblem(t *testing.T) {
+	tests := []struct {
+		name          string
+		req           *AcknowledgeProblemRequest
+		wantCode      int
+		wantBody      string
+		wantSucceeded int
+		wantFailed    int
+		wantErr       bool
+	}{
+		{
+			name:    "nil request",
+			req:     nil,
+			wantErr: true,
+		},
+		{
+			name:    "missing author",
+			req:     &AcknowledgeProblemRequest{Type: "Service", Comment: "on it"},
+			wantErr: true,
+		},
+		{
+			name:     "all acknowledged",
+			req:      &AcknowledgeProblemRequest{Type: "Service", Filter: `service.name=="load"`, Author: "admin", Comment: "on it"},
+			wantCode: http.StatusOK,
+			wantBody: `{"results":[` +
+				`{"code":200.0,"name":"host1!load","status":"Successfully acknowledged problem for object 'host1!load'."},` +
+				`{"code":200.0,"name":"host2!load","status":"Successfully acknowledged problem for object 'host2!load'."}]}`,
+			wantSucceeded: 2,
+		},
+		{
+			name:     "partially acknowledged",
+			req:      &AcknowledgeProblemRequest{Type: "Service", Filter: `service.name=="load"`, Author: "admin", Comment: "on it"},
+			wantCode: http.StatusInternalServerError,
+			wantBody: `{"results":[` +
+				`{"code":200.0,"name":"host1!load","status":"Successfully acknowledged problem for object 'host1!load'."},` +
+				`{"code":409.0,"name":"host2!load","status":"No problem for object 'host2!load'."}]}`,
+			wantSucceeded: 1,
+			wantFailed:    1,
+			wantErr:       true,
+		},
+	}
+
+	c := actions{cs: newTestClient()}
+	httpmock.ActivateNonDefault(c.cs.Client)
+	defer httpmock.DeactivateAndReset()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpmock.RegisterResponder(http.MethodPost, c.cs.Config.BaseURL+"/actions/acknowledge-problem",
+				httpmock.NewStringResponder(tt.wantCode, tt.wantBody))
+
+			got, err := c.AcknowledgeProblem(context.Background(), tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AcknowledgeProblem() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got.Succeeded()) != tt.wantSucceeded || len(got.Failed()) != tt.wantFailed {
+				t.Errorf("AcknowledgeProblem() succeeded = %v, failed = %v", got.Succeeded(), got.Failed())
+			}
+			if tt.wantFailed > 0 {
+				r, ok := got.ByName("host2!load")
+				if !ok || r.Succeeded() {
+					t.Errorf("ByName() = %v, %v, want the failed result", r, ok)
+				}
+			}
+		})
+	}
+}
+
+func Test_actions_ProcessCheckResult(t *testing.T) {
+	c := actions{cs: newTestClient()}
+	httpmock.ActivateNonDefault(c.cs.Client)
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(http.MethodPost, c.cs.Config.BaseURL+"/actions/process-check-result",
+		httpmock.NewStringResponder(http.StatusOK,
+			`{"results":[{"code":200.0,"status":"Successfully processed check result for object 'test!test'."}]}`))
+
+	got, err := c.ProcessCheckResult(context.Background(), testService())
+	if err != nil {
+		t.Fatalf("ProcessCheckResult() error = %v", err)
+	}
+	if len(got) != 1 || !got[0].Succeeded() {
+		t.Errorf("ProcessCheckResult() = %v, want a single succeeded result", got)
+	}
+
+	if _, err := c.ProcessCheckResult(context.Background(), nil); err == nil {
+		t.Error("ProcessCheckResult() expected error for a nil service")
+	}
+}
+
+func Test_actions_ProcessCheckResult_retry(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       *RetryPolicy
+		wantAttempts int
+	}{
+		{
+			name:         "not retried by default",
+			policy:       testRetryPolicy(3),
+			wantAttempts: 1,
+		},
+		{
+			name: "retried if non idempotent requests are",
+			policy: &RetryPolicy{
+				MaxAttempts:        3,
+				InitialBackoff:     time.Millisecond,
+				MaxBackoff:         time.Millisecond,
+				RetryNonIdempotent: true,
+			},
+			wantAttempts: 3,
+		},
+	}
+
+	c := actions{cs: newTestClient()}
+	httpmock.ActivateNonDefault(c.cs.Client)
+	defer httpmock.DeactivateAndReset()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int
+			httpmock.RegisterResponder(http.MethodPost, c.cs.Config.BaseURL+"/actions/process-check-result",
+				func(req *http.Request) (*http.Response, error) {
+					attempts++
+					return httpmock.NewStringResponse(http.StatusServiceUnavailable, `{"error":503,"status":"Icinga is reloading."}`), nil
+				})
+			c.cs.Config.Retry = tt.policy
+
+			if _, err := c.ProcessCheckResult(context.Background(), testService()); err == nil {
+				t.Fatal("ProcessCheckResult() error = nil, want the 503")
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("ProcessCheckResult() attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func Test_actions_ProcessCheckResult_filter(t *testing.T) {
+	c := actions{cs: newTestClient()}
+	httpmock.ActivateNonDefault(c.cs.Client)
+	defer httpmock.DeactivateAndReset()
+
+	var got UpdateCheckOutputRequest
+	httpmock.RegisterResponder(http.MethodPost, c.cs.Config.BaseURL+"/actions/process-check-result",
+		func(req *http.Request) (*http.Response, error) {
+			if err := json.NewDecoder(req.Body).Decode(&got); err != nil {
+				return nil, err
+			}
+			return httpmock.NewStringResponse(http.StatusOK, `{"results":[{"code":200.0}]}`), nil
+		})
+
+	srv := &Service{HostName: `web "1"`}
+	srv.Name = `disk \ /`
+	if _, err := c.ProcessCheckResult(context.Background(), srv); err != nil {
+		t.Fatalf("ProcessCheckResult() error = %v", err)
+	}
+	if want := "host.name==host_name && service.name==service_name"; got.Filter != want {
+		t.Errorf("ProcessCheckResult() sent filter = %q, want %q", got.Filter, want)
+	}
+	if got.FilterVars["host_name"] != srv.HostName || got.FilterVars["service_name"] != srv.Name {
+		t.Errorf("ProcessCheckResult() sent filter_vars = %v, want host_name %q and service_name %q",
+			got.FilterVars, srv.HostName, srv.Name)
+	}
+}
+
+func Test_actions_ProcessCheckResult_execution(t *testing.T) {
+	c := actions{cs: newTestClient()}
+	httpmock.ActivateNonDefault(c.cs.Client)
+	defer httpmock.DeactivateAndReset()
+
+	var got map[string]interface{}
+	httpmock.RegisterResponder(http.MethodPost, c.cs.Config.BaseURL+"/actions/process-check-result",
+		func(req *http.Request) (*http.Response, error) {
+			if err := json.NewDecoder(req.Body).Decode(&got); err != nil {
+				return nil, err
+			}
+			return httpmock.NewStringResponse(http.StatusOK, `{"results":[{"code":200.0}]}`), nil
+		})
+
+	srv := testService()
+	srv.LastCheckResult.ExecutionStart = NewIcingaTime(time.Unix(1700000000, 0))
+	srv.LastCheckResult.ExecutionEnd = NewIcingaTime(time.Unix(1700000001, 500000000))
+	srv.LastCheckResult.CheckSource = "runner1"
+	srv.LastCheckResult.TTL = IcingaDuration(2 * time.Minute)
+	if _, err := c.ProcessCheckResult(context.Background(), srv); err != nil {
+		t.Fatalf("ProcessCheckResult() error = %v", err)
+	}
+	want := map[string]interface{}{
+		"execution_start": 1700000000.0,
+		"execution_end":   1700000001.5,
+		"check_source":    "runner1",
+		"ttl":             120.0,
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("ProcessCheckResult() sent %s = %v, want %v", k, got[k], v)
+		}
+	}
+
+	// zero times and TTL are not sent, so icinga uses its defaults
+	srv.LastCheckResult = CheckResult{Output: "OK"}
+	got = nil
+	if _, err := c.ProcessCheckResult(context.Background(), srv); err != nil {
+		t.Fatalf("ProcessCheckResult() error = %v", err)
+	}
+	for k := range want {
+		if _, ok := got[k]; ok {
+			t.Errorf("ProcessCheckResult() sent %s = %v, want it omitted", k, got[k])
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// writableAttrs are the attributes of hosts and services which can be modified at runtime
+// through the icinga API. All other attributes are runtime state or only set on creation.
+var writableAttrs = map[string]bool{
+	"vars":                    true,
+	"check_command":           true,
+	"max_check_attempts":      true,
+	"check_period":            true,
+	"check_timeout":           true,
+	"check_interval":          true,
+	"retry_interval":          true,
+	"enable_notifications":    true,
+	"enable_active_checks":    true,
+	"enable_passive_checks":   true,
+	"enable_event_handler":    true,
+	"enable_flapping":         true,
+	"flapping_threshold_high": true,
+	"flapping_threshold_low":  true,
+	"flapping_ignore_states":  true,
+	"enable_perfdata":         true,
+	"event_command":           true,
+	"volatile":                true,
+	"command_endpoint":        true,
+	"notes":                   true,
+	"notes_url":               true,
+	"action_url":              true,
+	"icon_image":              true,
+	"icon_image_alt":          true,
+	"display_name":            true,
+	"address":                 true,
+	"address_6":               true,
+}
+
+// creationAttrs are the attributes of hosts and services which can be set when they are created:
+// the writable attributes and those icinga does not allow to modify afterwards.
+var creationAttrs = withAttrs(writableAttrs, "zone", "groups", "host_name")
+
+// withAttrs returns a copy of the attribute names with the given ones added.
+func withAttrs(attrs map[string]bool, names ...string) map[string]bool {
+	res := make(map[string]bool, len(attrs)+len(names))
+	for name := range attrs {
+		res[name] = true
+	}
+	for _, name := range names {
+		res[name] = true
+	}
+	return res
+}
+
+// isWritableAttr reports whether the attribute with the given name can be modified.
+// Single custom variables can be addressed as "vars.<name>".
+func isWritableAttr(name string) bool {
+	return writableAttrs[name] || strings.HasPrefix(name, "vars.") && len(name) > len("vars.")
+}
+
+// validateAttrs returns an error if any of the attributes cannot be modified.
+func validateAttrs(attrs map[string]interface{}) error {
+	if len(attrs) == 0 {
+		return fmt.Errorf("no attributes to update")
+	}
+	for name := range attrs {
+		if !isWritableAttr(name) {
+			return fmt.Errorf("attribute %q cannot be modified", name)
+		}
+	}
+	return nil
+}
+
+// objectAttrs returns the attributes of the given object with the given names, keyed by their
+// JSON name, e.g. the writableAttrs. Fields of embedding structs take precedence over fields of
+// embedded structs with the same name, like promoted fields in Go. If omitZero is set, attributes
+// with zero values are skipped.
+func objectAttrs(obj interface{}, names map[string]bool, omitZero bool) map[string]interface{} {
+	attrs := map[string]interface{}{}
+	v := reflect.Indirect(reflect.ValueOf(obj))
+	collectAttrs(v, names, attrs, map[string]bool{}, omitZero)
+	return attrs
+}
+
+// collectAttrs adds the fields of v with the given names to attrs. The shallow fields are added
+// first, then the embedded structs, so the names in seen are not overwritten by deeper fields.
+func collectAttrs(v reflect.Value, names map[string]bool, attrs map[string]interface{}, seen map[string]bool, omitZero bool) {
+	t := v.Type()
+	var embedded []reflect.Value
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			embedded = append(embedded, v.Field(i))
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		if !names[name] || omitZero && v.Field(i).IsZero() {
+			continue
+		}
+		attrs[name] = v.Field(i).Interface()
+	}
+	for _, e := range embedded {
+		collectAttrs(e, names, attrs, seen, omitZero)
+	}
+}
+
+// diffAttrs returns the writable attributes of the new object which differ from the old one.
+func diffAttrs(old, new interface{}) map[string]interface{} {
+	oldAttrs := objectAttrs(old, writableAttrs, false)
+	diff := map[string]interface{}{}
+	for name, value := range objectAttrs(new, writableAttrs, false) {
+		if !equalAttr(oldAttrs[name], value) {
+			diff[name] = value
+		}
+	}
+	return diff
+}
+
+// equalAttr reports whether the attribute values are equal. Nil and empty slices or maps are equal.
+func equalAttr(a, b interface{}) bool {
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Kind() != vb.Kind() {
+		return false
+	}
+	switch va.Kind() {
+	case reflect.Slice, reflect.Map:
+		return va.Len() == 0 && vb.Len() == 0
+	default:
+		return false
+	}
+}
+
+// updateAttrsRequest is the request body for modifying selected attributes of a config object.
+type updateAttrsRequest struct {
+	Attrs map[string]interface{} `json:"attrs"`
+}
+
+// updateAttrs modifies the given attributes of the object of the given type.
+func updateAttrs(ctx context.Context, ic *Icinga, typ, name string, attrs map[string]interface{}) error {
+	res := ic.Post().
+		Endpoint("objects").
+		Type(typ).
+		Object(name).
+		Body(&updateAttrsRequest{Attrs: attrs}).
+		Call(ctx)
+	return res.Error()
+}
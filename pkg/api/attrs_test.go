@@ -0,0 +1,209 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+)
+
+func Test_objectAttrs(t *testing.T) {
+	h := testHost()
+	h.CheckInterval = IcingaDuration(time.Minute)
+	h.Zone = "master"
+	h.Severity = 128
+	h.LastCheck = NewIcingaTime(time.Now())
+	h.LastCheckResult = CheckResult{Output: "OK"}
+
+	got := objectAttrs(h, writableAttrs, true)
+	want := map[string]interface{}{
+		"address":        h.Address,
+		"display_name":   h.DisplayName,
+		"check_interval": IcingaDuration(time.Minute),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("objectAttrs() = %v, want %v", got, want)
+	}
+
+	// the zone can only be set on creation
+	got = objectAttrs(h, creationAttrs, true)
+	want["zone"] = "master"
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("objectAttrs() = %v, want %v", got, want)
+	}
+}
+
+func Test_diffAttrs(t *testing.T) {
+	old := testHost()
+	old.Vars = map[string]interface{}{"os": "linux"}
+	old.Groups = nil
+
+	new := testHost()
+	new.Vars = map[string]interface{}{"os": "windows"}
+	new.Groups = []string{}
+	new.Address = ""
+	new.Severity = 64
+
+	got := diffAttrs(old, new)
+	want := map[string]interface{}{
+		"vars":    map[string]interface{}{"os": "windows"},
+		"address": "",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diffAttrs() = %v, want %v", got, want)
+	}
+}
+
+func Test_hosts_UpdateAttrs(t *testing.T) {
+	tests := []struct {
+		name      string
+		host      string
+		attrs     map[string]interface{}
+		wantAttrs map[string]interface{}
+		wantErr   bool
+	}{
+		{
+			name:    "empty host name",
+			host:    "",
+			attrs:   map[string]interface{}{"address": "10.0.0.1"},
+			wantErr: true,
+		},
+		{
+			name:    "no attributes",
+			host:    "test",
+			attrs:   nil,
+			wantErr: true,
+		},
+		{
+			name:    "read-only attribute",
+			host:    "test",
+			attrs:   map[string]interface{}{"last_check_result": nil},
+			wantErr: true,
+		},
+		{
+			name:    "creation-only attribute",
+			host:    "test",
+			attrs:   map[string]interface{}{"zone": "satellite"},
+			wantErr: true,
+		},
+		{
+			name:      "success",
+			host:      "test",
+			attrs:     map[string]interface{}{"address": "", "vars.os": "linux", "check_interval": IcingaDuration(time.Minute)},
+			wantAttrs: map[string]interface{}{"address": "", "vars.os": "linux", "check_interval": float64(60)},
+		},
+	}
+
+	c := hosts{ic: newTestClient()}
+	httpmock.ActivateNonDefault(c.ic.Client)
+	defer httpmock.DeactivateAndReset()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got map[string]interface{}
+			httpmock.RegisterResponder(http.MethodPost, c.ic.Config.BaseURL+"/objects/hosts/"+tt.host,
+				attrsRecorder(t, &got))
+
+			err := c.UpdateAttrs(context.Background(), tt.host, tt.attrs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UpdateAttrs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.wantAttrs) {
+				t.Errorf("UpdateAttrs() sent %v, want %v", got, tt.wantAttrs)
+			}
+		})
+	}
+}
+
+func Test_hosts_Patch(t *testing.T) {
+	c := hosts{ic: newTestClient()}
+	httpmock.ActivateNonDefault(c.ic.Client)
+	defer httpmock.DeactivateAndReset()
+
+	var got map[string]interface{}
+	httpmock.RegisterResponder(http.MethodPost, c.ic.Config.BaseURL+"/objects/hosts/test-host", attrsRecorder(t, &got))
+
+	old := testHost()
+	if err := c.Patch(context.Background(), old, testHost()); err != nil {
+		t.Fatalf("Patch() error = %v", err)
+	}
+	if n := httpmock.GetTotalCallCount(); n != 0 {
+		t.Fatalf("Patch() sent %d requests without changes, want none", n)
+	}
+
+	new := testHost()
+	new.DisplayName = "renamed"
+	new.LastCheck = NewIcingaTime(time.Now())
+	if err := c.Patch(context.Background(), old, new); err != nil {
+		t.Fatalf("Patch() error = %v", err)
+	}
+	if want := map[string]interface{}{"display_name": "renamed"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Patch() sent %v, want %v", got, want)
+	}
+
+	// zone and groups cannot be modified after creation, so their changes are not sent
+	httpmock.ZeroCallCounters()
+	new = testHost()
+	new.Zone = "satellite"
+	new.Groups = []string{"linux", "web"}
+	if err := c.Patch(context.Background(), old, new); err != nil {
+		t.Fatalf("Patch() error = %v", err)
+	}
+	if n := httpmock.GetTotalCallCount(); n != 0 {
+		t.Errorf("Patch() sent %d requests for creation-only attributes, want none", n)
+	}
+
+	new.Notes = "moved"
+	if err := c.Patch(context.Background(), old, new); err != nil {
+		t.Fatalf("Patch() error = %v", err)
+	}
+	if want := map[string]interface{}{"notes": "moved"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Patch() sent %v, want %v", got, want)
+	}
+}
+
+func Test_hosts_Update_ReadOnly(t *testing.T) {
+	c := hosts{ic: newTestClient()}
+	httpmock.ActivateNonDefault(c.ic.Client)
+	defer httpmock.DeactivateAndReset()
+
+	var got map[string]interface{}
+	httpmock.RegisterResponder(http.MethodPost, c.ic.Config.BaseURL+"/objects/hosts/test-host", attrsRecorder(t, &got))
+
+	h := testHost()
+	h.Severity = 128
+	h.LastCheckResult = CheckResult{Output: "OK"}
+	h.Executions = map[string]interface{}{"a": 1}
+	if err := c.Update(context.Background(), h); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	for _, name := range []string{"severity", "last_check_result", "executions", "last_check", "name", "type"} {
+		if _, ok := got[name]; ok {
+			t.Errorf("Update() sent read-only attribute %s", name)
+		}
+	}
+	if got["display_name"] != h.DisplayName {
+		t.Errorf("Update() sent %v, want the display name", got)
+	}
+}
+
+// attrsRecorder returns a responder which stores the attrs of the request body in got.
+func attrsRecorder(t *testing.T, got *map[string]interface{}) httpmock.Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		b, err := io.ReadAll(req.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body updateAttrsRequest
+		if err := json.Unmarshal(b, &body); err != nil {
+			t.Fatal(err)
+		}
+		*got = body.Attrs
+		return httpmock.NewStringResponse(http.StatusOK, `{"results":[{"code":200,"status":"Object was modified."}]}`), nil
+	}
+}
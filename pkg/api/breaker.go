@@ -0,0 +1,238 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFailureRatio     = 0.5
+	defaultMinRequests      = 10
+	defaultBreakerWindow    = time.Minute
+	defaultBreakerCoolDown  = 30 * time.Second
+	defaultHalfOpenRequests = 1
+)
+
+// ErrCircuitOpen is returned by Request.Call without contacting the icinga API,
+// while the circuit breaker of the client is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open, icinga API is considered unavailable")
+
+// CircuitState is the state of a circuit breaker.
+type CircuitState int
+
+const (
+	// CircuitClosed lets all requests through.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects all requests with ErrCircuitOpen.
+	CircuitOpen
+	// CircuitHalfOpen lets a limited number of trial requests through.
+	CircuitHalfOpen
+)
+
+// String returns the name of the state.
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreakerConfig configures the circuit breaker of the client. Every call counts once,
+// after retries and failover: connection errors, client timeouts and 502, 503 and 504 responses
+// count as failures. Calls ended by the context of the caller count as neither.
+type CircuitBreakerConfig struct {
+	// FailureRatio is the ratio of failed requests within a window which opens the circuit. Defaults to 0.5.
+	FailureRatio float64
+	// MinRequests is the number of requests within a window before the failure ratio is evaluated. Defaults to 10.
+	MinRequests int
+	// Window is the interval after which the counts are reset while the circuit is closed. Defaults to 1m.
+	Window time.Duration
+	// CoolDown is the time the circuit stays open before trial requests are let through. Defaults to 30s.
+	CoolDown time.Duration
+	// HalfOpenRequests is the number of trial requests in the half-open state.
+	// The circuit closes once all of them succeeded. Defaults to 1.
+	HalfOpenRequests int
+	// OnStateChange is called whenever the circuit changes its state, e.g. to shed load or to start spooling.
+	OnStateChange func(from, to CircuitState)
+}
+
+// breakerOutcome is the outcome of a call for the circuit breaker.
+type breakerOutcome int
+
+const (
+	breakerSuccess breakerOutcome = iota
+	breakerFailure
+	// breakerIgnored is neither a success nor a failure, e.g. a call canceled by its caller
+	breakerIgnored
+)
+
+// circuitBreaker stops sending requests to an unavailable icinga API.
+// A single breaker is shared by all clients created from the same Icinga client.
+type circuitBreaker struct {
+	cfg CircuitBreakerConfig
+
+	mu    sync.Mutex
+	state CircuitState
+	// generation is incremented on every state change, so late results of requests
+	// started in a previous state are ignored
+	generation  uint64
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	// trials and successes count the requests of the half-open state
+	trials    int
+	successes int
+}
+
+// newCircuitBreaker creates a circuit breaker for the given config, or nil if none is configured.
+func newCircuitBreaker(cfg *CircuitBreakerConfig) *circuitBreaker {
+	if cfg == nil {
+		return nil
+	}
+	c := *cfg
+	if c.FailureRatio <= 0 {
+		c.FailureRatio = defaultFailureRatio
+	}
+	c.MinRequests = intOrDefault(c.MinRequests, defaultMinRequests)
+	c.Window = durationOrDefault(c.Window, defaultBreakerWindow)
+	c.CoolDown = durationOrDefault(c.CoolDown, defaultBreakerCoolDown)
+	c.HalfOpenRequests = intOrDefault(c.HalfOpenRequests, defaultHalfOpenRequests)
+	return &circuitBreaker{cfg: c, windowStart: time.Now()}
+}
+
+// State returns the current state of the circuit.
+func (b *circuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// allow reports whether a request may be sent. If so, the returned function must be called
+// with the outcome of the request. A nil breaker allows all requests.
+func (b *circuitBreaker) allow() (done func(outcome breakerOutcome), err error) {
+	if b == nil {
+		return func(breakerOutcome) {}, nil
+	}
+
+	b.mu.Lock()
+	now := time.Now()
+	var notify func()
+	switch b.state {
+	case CircuitOpen:
+		if now.Sub(b.openedAt) < b.cfg.CoolDown {
+			b.mu.Unlock()
+			return nil, ErrCircuitOpen
+		}
+		notify = b.setState(CircuitHalfOpen, now)
+		fallthrough
+	case CircuitHalfOpen:
+		if b.trials >= b.cfg.HalfOpenRequests {
+			b.mu.Unlock()
+			runNotify(notify)
+			return nil, ErrCircuitOpen
+		}
+		b.trials++
+	case CircuitClosed:
+		if now.Sub(b.windowStart) >= b.cfg.Window {
+			b.windowStart, b.requests, b.failures = now, 0, 0
+		}
+	}
+	generation := b.generation
+	b.mu.Unlock()
+	runNotify(notify)
+
+	return func(outcome breakerOutcome) { b.record(generation, outcome) }, nil
+}
+
+// record counts the outcome of a request started in the given generation.
+// An ignored trial request frees its slot for another trial.
+func (b *circuitBreaker) record(generation uint64, outcome breakerOutcome) {
+	b.mu.Lock()
+	if generation != b.generation {
+		b.mu.Unlock()
+		return
+	}
+	if outcome == breakerIgnored {
+		if b.state == CircuitHalfOpen {
+			b.trials--
+		}
+		b.mu.Unlock()
+		return
+	}
+	failed := outcome == breakerFailure
+
+	now := time.Now()
+	var notify func()
+	switch b.state {
+	case CircuitClosed:
+		b.requests++
+		if failed {
+			b.failures++
+		}
+		if b.requests >= b.cfg.MinRequests &&
+			float64(b.failures)/float64(b.requests) >= b.cfg.FailureRatio {
+			notify = b.setState(CircuitOpen, now)
+		}
+	case CircuitHalfOpen:
+		if failed {
+			notify = b.setState(CircuitOpen, now)
+			break
+		}
+		b.successes++
+		if b.successes >= b.cfg.HalfOpenRequests {
+			notify = b.setState(CircuitClosed, now)
+		}
+	case CircuitOpen:
+	}
+	b.mu.Unlock()
+	runNotify(notify)
+}
+
+// setState changes the state and resets the counters. It must be called with the lock held
+// and returns the notification of the state change, which must be run after unlocking.
+func (b *circuitBreaker) setState(to CircuitState, now time.Time) func() {
+	from := b.state
+	b.state = to
+	b.generation++
+	b.windowStart, b.requests, b.failures = now, 0, 0
+	b.trials, b.successes = 0, 0
+	if to == CircuitOpen {
+		b.openedAt = now
+	}
+
+	if b.cfg.OnStateChange == nil {
+		return nil
+	}
+	return func() { b.cfg.OnStateChange(from, to) }
+}
+
+func runNotify(notify func()) {
+	if notify != nil {
+		notify()
+	}
+}
+
+// outcomeOf returns the outcome of the call for the circuit breaker. Errors of the http.Client
+// wrap context.DeadlineExceeded for its own Timeout as well, so only the context of the caller
+// tells a canceled call from a timed out one.
+func outcomeOf(ctx context.Context, res *Result) breakerOutcome {
+	switch {
+	case res.err == nil:
+		return breakerSuccess
+	case ctx.Err() != nil:
+		return breakerIgnored
+	case res.transportErr || isUnavailableStatus(res.statusCode):
+		return breakerFailure
+	default:
+		return breakerSuccess
+	}
+}
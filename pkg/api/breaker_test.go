@@ -0,0 +1,208 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/jarcoal/httpmock"
+)
+
+func Test_circuitBreaker(t *testing.T) {
+	var transitions []string
+	b := newCircuitBreaker(&CircuitBreakerConfig{
+		MinRequests: 4,
+		CoolDown:    20 * time.Millisecond,
+		OnStateChange: func(from, to CircuitState) {
+			transitions = append(transitions, from.String()+"->"+to.String())
+		},
+	})
+
+	call := func(failed bool) error {
+		done, err := b.allow()
+		if err != nil {
+			return err
+		}
+		if failed {
+			done(breakerFailure)
+		} else {
+			done(breakerSuccess)
+		}
+		return nil
+	}
+
+	// two of four requests failed: the failure ratio of 0.5 is reached
+	for _, failed := range []bool{false, true, false, true} {
+		if err := call(failed); err != nil {
+			t.Fatalf("allow() error = %v while closed", err)
+		}
+	}
+	if b.State() != CircuitOpen {
+		t.Fatalf("State() = %s, want open", b.State())
+	}
+	if err := call(false); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("allow() error = %v, want ErrCircuitOpen", err)
+	}
+
+	// the failed trial request opens the circuit again
+	time.Sleep(30 * time.Millisecond)
+	if err := call(true); err != nil {
+		t.Fatalf("allow() error = %v after cool-down", err)
+	}
+	if b.State() != CircuitOpen {
+		t.Fatalf("State() = %s, want open after failed trial", b.State())
+	}
+
+	// the successful trial request closes the circuit
+	time.Sleep(30 * time.Millisecond)
+	done, err := b.allow()
+	if err != nil {
+		t.Fatalf("allow() error = %v after cool-down", err)
+	}
+	if _, err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("allow() error = %v, want ErrCircuitOpen for a second trial", err)
+	}
+	done(breakerSuccess)
+	if b.State() != CircuitClosed {
+		t.Fatalf("State() = %s, want closed", b.State())
+	}
+
+	want := []string{"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed"}
+	if len(transitions) != len(want) {
+		t.Fatalf("transitions = %v, want %v", transitions, want)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Errorf("transitions = %v, want %v", transitions, want)
+			break
+		}
+	}
+}
+
+func TestRequest_Call_CircuitOpen(t *testing.T) {
+	l := logr.Discard()
+	c, err := New(&Config{
+		BaseURL:        "https://icinga-server:5665/v1",
+		CircuitBreaker: &CircuitBreakerConfig{MinRequests: 2, CoolDown: time.Minute},
+	}, &l)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	httpmock.ActivateNonDefault(c.Client)
+	defer httpmock.DeactivateAndReset()
+
+	url := c.Config.BaseURL + "/objects/hosts/test"
+	httpmock.RegisterResponder(http.MethodGet, url, httpmock.NewStringResponder(http.StatusServiceUnavailable, ""))
+
+	for i := 0; i < 5; i++ {
+		err := c.Get().Endpoint("objects").Type("hosts").Object("test").Call(context.Background()).Error()
+		if err == nil {
+			t.Fatalf("Call() expected error")
+		}
+		if i >= 2 && !errors.Is(err, ErrCircuitOpen) {
+			t.Errorf("Call() error = %v, want ErrCircuitOpen", err)
+		}
+	}
+	if got := httpmock.GetCallCountInfo()["GET "+url]; got != 2 {
+		t.Errorf("icinga api called %d times, want 2", got)
+	}
+	if c.CircuitState() != CircuitOpen {
+		t.Errorf("CircuitState() = %s, want open", c.CircuitState())
+	}
+}
+
+func Test_circuitBreaker_ignored(t *testing.T) {
+	b := newCircuitBreaker(&CircuitBreakerConfig{MinRequests: 1, CoolDown: 10 * time.Millisecond})
+	done, _ := b.allow()
+	done(breakerFailure)
+	if b.State() != CircuitOpen {
+		t.Fatalf("State() = %s, want open", b.State())
+	}
+
+	// a canceled trial request neither closes nor opens the circuit, and frees its slot
+	time.Sleep(20 * time.Millisecond)
+	done, err := b.allow()
+	if err != nil {
+		t.Fatalf("allow() error = %v after cool-down", err)
+	}
+	done(breakerIgnored)
+	if b.State() != CircuitHalfOpen {
+		t.Fatalf("State() = %s, want half-open after an ignored trial", b.State())
+	}
+	done, err = b.allow()
+	if err != nil {
+		t.Fatalf("allow() error = %v, want another trial after an ignored one", err)
+	}
+	done(breakerSuccess)
+	if b.State() != CircuitClosed {
+		t.Fatalf("State() = %s, want closed", b.State())
+	}
+
+	// ignored requests are not counted while closed
+	for i := 0; i < 3; i++ {
+		done, _ := b.allow()
+		done(breakerIgnored)
+	}
+	if b.State() != CircuitClosed {
+		t.Errorf("State() = %s, want closed after ignored requests", b.State())
+	}
+}
+
+func TestRequest_Call_CircuitOpenOnTimeout(t *testing.T) {
+	var calls int32
+	srv := newHangingServer(t, 100, &calls)
+	l := logr.Discard()
+	c, err := New(&Config{
+		BaseURL:        srv.URL + "/v1",
+		Timeout:        20 * time.Millisecond,
+		CircuitBreaker: &CircuitBreakerConfig{MinRequests: 2, CoolDown: time.Minute},
+	}, &l)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		err := c.Get().Endpoint("objects").Type("hosts").Call(context.Background()).Error()
+		if err == nil {
+			t.Fatalf("Call() error = nil, want a timeout")
+		}
+		if i == 2 && !errors.Is(err, ErrCircuitOpen) {
+			t.Errorf("Call() error = %v, want ErrCircuitOpen after timeouts", err)
+		}
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("icinga api called %d times, want 2", got)
+	}
+}
+
+func TestRequest_Call_CircuitCountsCalls(t *testing.T) {
+	l := logr.Discard()
+	c, err := New(&Config{
+		BaseURL:        "https://icinga-server:5665/v1",
+		Retry:          testRetryPolicy(3),
+		CircuitBreaker: &CircuitBreakerConfig{MinRequests: 2, CoolDown: time.Minute},
+	}, &l)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	httpmock.ActivateNonDefault(c.Client)
+	defer httpmock.DeactivateAndReset()
+
+	url := c.Config.BaseURL + "/objects/hosts/test"
+	httpmock.RegisterResponder(http.MethodGet, url, httpmock.NewStringResponder(http.StatusServiceUnavailable, ""))
+
+	// a single call with all retries counts as a single failure
+	if err := c.Get().Endpoint("objects").Type("hosts").Object("test").Call(context.Background()).Error(); err == nil {
+		t.Fatalf("Call() expected error")
+	}
+	if got := httpmock.GetCallCountInfo()["GET "+url]; got != 3 {
+		t.Errorf("icinga api called %d times, want 3", got)
+	}
+	if c.CircuitState() != CircuitClosed {
+		t.Errorf("CircuitState() = %s, want closed after a single call", c.CircuitState())
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"time"
 
 	"github.com/go-logr/logr"
-	"github.com/puffitos/goicinga/internal/util"
 )
 
 type Client interface {
@@ -21,39 +20,130 @@ type Icinga struct {
 	Config *Config
 	Client *http.Client
 	Log    *logr.Logger
+
+	// limiter is shared by all copies of the client, see named
+	limiter *limiter
+	// redactor prepares bodies for logging
+	redactor *redactor
+	// endpoints tracks the health of the base URLs, shared by all copies of the client
+	endpoints *endpointPool
+	// breaker is shared by all copies of the client
+	breaker *circuitBreaker
 }
 
 // Config holds the configuration for the icinga client
 type Config struct {
 	// BaseURL the URL of the icinga API (including the port)
 	BaseURL string
-	// APIUser is the username for the icinga API
+	// Endpoints are additional URLs of the icinga API, e.g. the second master of a HA setup.
+	// Requests are sent to BaseURL first and fail over to the endpoints in order, if an
+	// endpoint is unreachable or responds with 502, 503 or 504.
+	Endpoints []string
+	// EndpointCoolDown is the time a failed endpoint is tried last. Defaults to 30s.
+	EndpointCoolDown time.Duration
+	// RoundRobinReads distributes GET requests across all healthy endpoints.
+	RoundRobinReads bool
+	// APIUser is the username for the icinga API. If both APIUser and APIPass are empty,
+	// no basic auth header is sent, e.g. when authenticating with a client certificate.
 	APIUser string
 	// APIPass is the password for the icinga API
 	APIPass string
+	// Credentials provides the credentials for every request. If set, APIUser and APIPass are ignored.
+	Credentials CredentialProvider
 	// Timeout is the global timeout for all API requests
 	Timeout time.Duration
-	// CertPath is the endpoint to the certificate used for TLS
+	// CertPath is the path to the CA certificate used to verify the icinga API.
+	// If empty, the certificate is verified against the system pool.
 	CertPath string
+	// InsecureSkipVerify disables the verification of the icinga API certificate. Use with care.
+	InsecureSkipVerify bool
+	// ServerName overrides the name the icinga API certificate is verified against.
+	// Icinga certificates are issued for the node name, which may differ from the DNS name in BaseURL.
+	ServerName string
+	// PinnedFingerprints are the hex encoded SHA-256 fingerprints of the accepted API certificates.
+	// If set, the certificate must match one of them, even if InsecureSkipVerify is set.
+	PinnedFingerprints []string
+	// ReloadCertificates reloads the CA and client certificate files for new connections
+	// whenever they change, e.g. after the automatic renewal of an icinga agent certificate.
+	// Certificates passed as PEM are not reloaded.
+	ReloadCertificates bool
+	// ClientCertPath is the path to the client certificate used to authenticate against the icinga API
+	ClientCertPath string
+	// ClientKeyPath is the path to the private key of the client certificate
+	ClientKeyPath string
+	// ClientCertPEM is the PEM encoded client certificate. Takes precedence over ClientCertPath.
+	ClientCertPEM []byte
+	// ClientKeyPEM is the PEM encoded private key of the client certificate. Takes precedence over ClientKeyPath.
+	ClientKeyPEM []byte
+	// Retry is the retry policy applied to all requests. Nil disables retries.
+	Retry *RetryPolicy
+	// CircuitBreaker stops sending requests while the icinga API is unavailable, and fails
+	// them with ErrCircuitOpen instead. Nil disables the circuit breaker.
+	CircuitBreaker *CircuitBreakerConfig
+	// QPS is the maximum number of requests per second sent to the icinga API. Zero disables rate limiting.
+	QPS float64
+	// Burst is the maximum number of requests which may be sent at once above QPS. Defaults to QPS.
+	Burst int
+	// MaxInFlight is the maximum number of concurrent requests. Zero disables the limit.
+	MaxInFlight int
+	// OnWait is called with the time each request had to wait for the rate and concurrency limits.
+	OnWait func(time.Duration)
+	// Transport tunes the connection pooling of the client. Ignored if RoundTripper is set.
+	Transport TransportConfig
+	// Logging controls how request and response bodies are logged, e.g. which attributes are redacted.
+	Logging LogOptions
+	// RoundTripper replaces the transport created by the client, e.g. to add instrumentation.
+	// The TLS and Transport settings of the config are not applied to a custom RoundTripper.
+	RoundTripper http.RoundTripper
 }
 
-// New creates a new icinga client with the passed configuration and logger
-func New(config *Config, log *logr.Logger) *Icinga {
+// New creates a new icinga client with the passed configuration and logger.
+// Returns an error if the configuration is invalid.
+func New(config *Config, log *logr.Logger) (*Icinga, error) {
 	if log == nil {
 		l := logr.Discard()
 		log = &l
 	}
 
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	transport, err := newTransport(config)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Icinga{
 		Config: config,
 		Client: &http.Client{
-			Timeout: config.Timeout,
-			Transport: &http.Transport{
-				TLSClientConfig: util.NewTLSConfig(config.CertPath),
-			},
+			Timeout:   config.Timeout,
+			Transport: transport,
 		},
-		Log: log,
+		Log:       log,
+		limiter:   newLimiter(config),
+		redactor:  newRedactor(config.Logging),
+		endpoints: newEndpointPool(config),
+		breaker:   newCircuitBreaker(config.CircuitBreaker),
+	}, nil
+}
+
+// CircuitState returns the state of the circuit breaker. Without a circuit breaker,
+// the circuit is always closed.
+func (c *Icinga) CircuitState() CircuitState {
+	if c.breaker == nil {
+		return CircuitClosed
 	}
+	return c.breaker.State()
+}
+
+// named returns a copy of the client which logs with the given name, but shares
+// the http.Client and the limits with the original client.
+func (c *Icinga) named(name string) *Icinga {
+	l := c.Log.WithName(name)
+	cp := *c
+	cp.Log = &l
+	return &cp
 }
 
 // Verb creates a new request for the given verb.
@@ -1 +1,121 @@
 package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+)
+
+func TestRequest_Call_BasicAuth(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     string
+		pass     string
+		wantAuth bool
+	}{
+		{
+			name:     "basic auth",
+			user:     "root",
+			pass:     "root",
+			wantAuth: true,
+		},
+		{
+			name:     "no credentials",
+			user:     "",
+			pass:     "",
+			wantAuth: false,
+		},
+	}
+
+	c := newTestClient()
+	httpmock.ActivateNonDefault(c.Client)
+	defer httpmock.DeactivateAndReset()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c.Config.APIUser, c.Config.APIPass = tt.user, tt.pass
+
+			var gotAuth bool
+			httpmock.RegisterResponder(http.MethodGet, c.Config.BaseURL+"/objects/hosts/test",
+				func(req *http.Request) (*http.Response, error) {
+					_, _, gotAuth = req.BasicAuth()
+					return httpmock.NewStringResponse(http.StatusOK, testHostQueryResult()), nil
+				})
+
+			if err := c.Get().Endpoint("objects").Type("hosts").Object("test").Call(context.Background()).Error(); err != nil {
+				t.Fatalf("Call() error = %v", err)
+			}
+			if gotAuth != tt.wantAuth {
+				t.Errorf("Call() sent basic auth = %v, want %v", gotAuth, tt.wantAuth)
+			}
+		})
+	}
+}
+
+func TestRequest_Call_StatusError(t *testing.T) {
+	tests := []struct {
+		name      string
+		code      int
+		body      string
+		wantCheck func(error) bool
+	}{
+		{
+			name:      "icinga error",
+			code:      http.StatusNotFound,
+			body:      `{"error":404,"status":"No objects found."}`,
+			wantCheck: IsNotFound,
+		},
+		{
+			name:      "html body",
+			code:      http.StatusUnauthorized,
+			body:      `<h1>Unauthorized. Please check your user credentials.</h1>`,
+			wantCheck: IsUnauthorized,
+		},
+		{
+			name:      "empty body",
+			code:      http.StatusForbidden,
+			body:      ``,
+			wantCheck: IsForbidden,
+		},
+	}
+
+	c := newTestClient()
+	httpmock.ActivateNonDefault(c.Client)
+	defer httpmock.DeactivateAndReset()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpmock.RegisterResponder(http.MethodGet, c.Config.BaseURL+"/objects/hosts/test",
+				httpmock.NewStringResponder(tt.code, tt.body))
+
+			err := c.Get().Endpoint("objects").Type("hosts").Object("test").Call(context.Background()).Error()
+			if !tt.wantCheck(err) {
+				t.Errorf("Call() error = %v, want status %d", err, tt.code)
+			}
+		})
+	}
+}
+
+func TestResult_Succeeded(t *testing.T) {
+	c := newTestClient()
+	httpmock.ActivateNonDefault(c.Client)
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(http.MethodDelete, c.Config.BaseURL+"/objects/hosts/test",
+		httpmock.NewStringResponder(http.StatusInternalServerError, `{"results":[`+
+			`{"code":200,"name":"test","status":"Object was deleted.","type":"Host"},`+
+			`{"code":500,"name":"test!load","status":"Object could not be deleted.","type":"Service"}]}`))
+
+	res := c.Delete().Endpoint("objects").Type("hosts").Object("test").Call(context.Background())
+	var errs *ResultErrors
+	if !errors.As(res.Error(), &errs) || len(errs.Errors) != 1 || errs.Errors[0].Name != "test!load" {
+		t.Fatalf("Error() = %v, want the failed service only", res.Error())
+	}
+	succeeded := res.Succeeded()
+	if len(succeeded) != 1 || succeeded[0].Name != "test" {
+		t.Errorf("Succeeded() = %v, want the deleted host", succeeded)
+	}
+}
@@ -33,16 +33,22 @@ func (c *ClientSet) Actions() Actions {
 	return c.actions
 }
 
-// NewClientSet creates a new client with the given configuration
-func NewClientSet(config *Config, log *logr.Logger) *ClientSet {
-	if log == nil {
-		l := logr.Discard()
-		log = &l
+// NewClientSet creates a new client with the given configuration.
+// All clients of the set share the same Icinga client, and thus its connection pool and limits.
+func NewClientSet(config *Config, log *logr.Logger) (*ClientSet, error) {
+	ic, err := New(config, log)
+	if err != nil {
+		return nil, err
 	}
+	return NewClientSetFor(ic), nil
+}
 
+// NewClientSetFor creates a new client set on top of an existing Icinga client,
+// sharing its connection pool and limits.
+func NewClientSetFor(ic *Icinga) *ClientSet {
 	return &ClientSet{
-		services: newServicesClient(config, log),
-		hosts:    newHostsClient(config, log),
-		actions:  newActionsClient(config, log),
+		services: newServicesClient(ic),
+		hosts:    newHostsClient(ic),
+		actions:  newActionsClient(ic),
 	}
 }
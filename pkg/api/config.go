@@ -0,0 +1,271 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Environment variables read by LoadConfig. They take precedence over the config file.
+const (
+	// EnvConfig is the path to the config file, used if no path is passed to LoadConfig.
+	EnvConfig = "ICINGA_CONFIG"
+	// EnvContext is the name of the context to use, overriding the current context of the config file.
+	EnvContext            = "ICINGA_CONTEXT"
+	EnvURL                = "ICINGA_URL"
+	EnvUser               = "ICINGA_USER"
+	EnvPassword           = "ICINGA_PASSWORD"
+	EnvPasswordFile       = "ICINGA_PASSWORD_FILE"
+	EnvCAFile             = "ICINGA_CA_FILE"
+	EnvClientCert         = "ICINGA_CLIENT_CERT"
+	EnvClientKey          = "ICINGA_CLIENT_KEY"
+	EnvServerName         = "ICINGA_SERVER_NAME"
+	EnvInsecureSkipVerify = "ICINGA_INSECURE_SKIP_VERIFY"
+	EnvTimeout            = "ICINGA_TIMEOUT"
+)
+
+// apiVersionPathSuffix is the path suffix every base URL of the icinga API must have.
+const apiVersionPathSuffix = "/v1"
+
+// ConfigFile is the format of the config file read by LoadConfig. Like a kubeconfig,
+// it may hold the settings of multiple icinga instances, each in its own named context.
+// The file may be written in YAML or JSON.
+type ConfigFile struct {
+	// CurrentContext is the name of the context used if none is requested explicitly.
+	CurrentContext string `yaml:"current-context" json:"current-context"`
+	// Contexts are the named icinga instances.
+	Contexts []ConfigContext `yaml:"contexts" json:"contexts"`
+}
+
+// ConfigContext holds the settings of a single icinga instance.
+type ConfigContext struct {
+	Name string `yaml:"name" json:"name"`
+	// URL is the base URL of the icinga API, including the /v1 suffix.
+	URL string `yaml:"url" json:"url"`
+	// Endpoints are the URLs of further icinga masters, which are used if URL is unavailable.
+	Endpoints    []string `yaml:"endpoints" json:"endpoints"`
+	User         string   `yaml:"user" json:"user"`
+	Password     string   `yaml:"password" json:"password"`
+	PasswordFile string   `yaml:"password-file" json:"password-file"`
+	CAFile       string   `yaml:"ca-file" json:"ca-file"`
+	ClientCert   string   `yaml:"client-cert" json:"client-cert"`
+	ClientKey    string   `yaml:"client-key" json:"client-key"`
+	ServerName   string   `yaml:"server-name" json:"server-name"`
+	// InsecureSkipVerify disables the verification of the API certificate.
+	InsecureSkipVerify bool `yaml:"insecure-skip-verify" json:"insecure-skip-verify"`
+	// Timeout is either a duration like 10s or a number of seconds.
+	Timeout string `yaml:"timeout" json:"timeout"`
+}
+
+// LoadConfig builds a Config from a config file and ICINGA_* environment variables.
+//
+// The settings are resolved in the following order, the first one found wins:
+//  1. the environment variables, e.g. ICINGA_URL
+//  2. the selected context of the config file
+//
+// The config file is read from path, or from ICINGA_CONFIG if path is empty. Without a
+// config file, only the environment variables are used. The context is selected by name,
+// falling back to ICINGA_CONTEXT, the current-context of the file and finally the only
+// context of the file. A password set at one level takes precedence over a password file
+// set at the same level. The resulting config is validated before it is returned.
+func LoadConfig(path, context string) (*Config, error) {
+	var ctx ConfigContext
+	if path == "" {
+		path = os.Getenv(EnvConfig)
+	}
+	if path != "" {
+		f, err := readConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if context == "" {
+			context = os.Getenv(EnvContext)
+		}
+		c, err := f.context(context)
+		if err != nil {
+			return nil, fmt.Errorf("config file %s: %w", path, err)
+		}
+		ctx = *c
+	}
+
+	if err := ctx.applyEnv(); err != nil {
+		return nil, err
+	}
+
+	cfg, err := ctx.config()
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// readConfigFile reads and parses the config file at the given path.
+func readConfigFile(path string) (*ConfigFile, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	// YAML is a superset of JSON, so both formats are parsed by the YAML decoder.
+	var f ConfigFile
+	if err := yaml.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return &f, nil
+}
+
+// context returns the context with the given name, the current context if the name
+// is empty, or the only context if the file has exactly one.
+func (f *ConfigFile) context(name string) (*ConfigContext, error) {
+	if name == "" {
+		name = f.CurrentContext
+	}
+	if name == "" {
+		if len(f.Contexts) == 1 {
+			return &f.Contexts[0], nil
+		}
+		return nil, errors.New("no context selected and no current-context set")
+	}
+	for i := range f.Contexts {
+		if f.Contexts[i].Name == name {
+			return &f.Contexts[i], nil
+		}
+	}
+	return nil, fmt.Errorf("context %q not found", name)
+}
+
+// applyEnv overrides the settings of the context with the ones set in the environment.
+func (c *ConfigContext) applyEnv() error {
+	setFromEnv(&c.URL, EnvURL)
+	setFromEnv(&c.User, EnvUser)
+	setFromEnv(&c.CAFile, EnvCAFile)
+	setFromEnv(&c.ClientCert, EnvClientCert)
+	setFromEnv(&c.ClientKey, EnvClientKey)
+	setFromEnv(&c.ServerName, EnvServerName)
+	setFromEnv(&c.Timeout, EnvTimeout)
+
+	// a password from the environment replaces both the password and the password file of the context
+	if v, ok := os.LookupEnv(EnvPasswordFile); ok {
+		c.Password, c.PasswordFile = "", v
+	}
+	if v, ok := os.LookupEnv(EnvPassword); ok {
+		c.Password, c.PasswordFile = v, ""
+	}
+
+	if v, ok := os.LookupEnv(EnvInsecureSkipVerify); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid value %q for %s: %w", v, EnvInsecureSkipVerify, err)
+		}
+		c.InsecureSkipVerify = b
+	}
+	return nil
+}
+
+// config converts the context into a Config.
+func (c *ConfigContext) config() (*Config, error) {
+	timeout, err := parseTimeout(c.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	// the password file is watched, so the rotated password is picked up without a restart
+	var creds CredentialProvider
+	if c.Password == "" && c.PasswordFile != "" {
+		creds, err = FileCredentials(c.User, c.PasswordFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Config{
+		BaseURL:            c.URL,
+		Endpoints:          c.Endpoints,
+		APIUser:            c.User,
+		APIPass:            c.Password,
+		Credentials:        creds,
+		Timeout:            timeout,
+		CertPath:           c.CAFile,
+		ClientCertPath:     c.ClientCert,
+		ClientKeyPath:      c.ClientKey,
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}, nil
+}
+
+// parseTimeout parses a timeout given either as a duration or as a number of seconds.
+func parseTimeout(v string) (time.Duration, error) {
+	if v == "" {
+		return 0, nil
+	}
+	if s, err := strconv.ParseFloat(v, 64); err == nil {
+		return time.Duration(s * float64(time.Second)), nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout %q: %w", v, err)
+	}
+	return d, nil
+}
+
+func setFromEnv(field *string, key string) {
+	if v, ok := os.LookupEnv(key); ok {
+		*field = v
+	}
+}
+
+// Validate checks the config for errors which would otherwise only show up
+// once the first request is sent, like a base URL without the /v1 suffix.
+func (c *Config) Validate() error {
+	if c.BaseURL == "" {
+		return errors.New("invalid config: BaseURL must not be empty")
+	}
+	for _, u := range append([]string{c.BaseURL}, c.Endpoints...) {
+		if err := validateURL(u); err != nil {
+			return err
+		}
+	}
+
+	hasCert := c.ClientCertPath != "" || c.ClientCertPEM != nil
+	hasKey := c.ClientKeyPath != "" || c.ClientKeyPEM != nil
+	if hasCert != hasKey {
+		return errors.New("invalid config: client certificate and key must be set together")
+	}
+
+	if c.Timeout < 0 {
+		return errors.New("invalid config: Timeout must not be negative")
+	}
+	if c.QPS < 0 || c.Burst < 0 || c.MaxInFlight < 0 {
+		return errors.New("invalid config: QPS, Burst and MaxInFlight must not be negative")
+	}
+	if c.Retry != nil && (c.Retry.Jitter < 0 || c.Retry.Jitter > 1) {
+		return errors.New("invalid config: Retry.Jitter must be between 0 and 1")
+	}
+	return nil
+}
+
+// validateURL checks that the given URL points to the v1 API of an icinga instance.
+func validateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid config: URL %s: %w", raw, err)
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return fmt.Errorf("invalid config: URL %s must use the https or http scheme", raw)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("invalid config: URL %s has no host", raw)
+	}
+	if !strings.HasSuffix(u.Path, apiVersionPathSuffix) {
+		return fmt.Errorf("invalid config: URL %s must end with %s", raw, apiVersionPathSuffix)
+	}
+	return nil
+}
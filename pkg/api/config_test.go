@@ -0,0 +1,230 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testConfigFile = `
+current-context: prod
+contexts:
+  - name: prod
+    url: https://icinga-master:5665/v1
+    user: root
+    password: prod-secret
+    ca-file: /etc/icinga2/ca.crt
+    timeout: 10s
+  - name: staging
+    url: https://icinga-staging:5665/v1
+    user: staging
+    password-file: %s
+    server-name: icinga-staging-master
+    timeout: "2.5"
+`
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	passwordFile := filepath.Join(dir, "password")
+	writeTestFile(t, passwordFile, "file-secret\n")
+	configFile := filepath.Join(dir, "config.yaml")
+	writeTestFile(t, configFile, fmt.Sprintf(testConfigFile, passwordFile))
+	jsonConfigFile := filepath.Join(dir, "config.json")
+	writeTestFile(t, jsonConfigFile, `{"contexts":[{"name":"json","url":"https://icinga-json:5665/v1","user":"json"}]}`)
+
+	tests := []struct {
+		name    string
+		path    string
+		context string
+		env     map[string]string
+		want    *Config
+		wantErr bool
+	}{
+		{
+			name: "current context",
+			path: configFile,
+			want: &Config{
+				BaseURL:  "https://icinga-master:5665/v1",
+				APIUser:  "root",
+				APIPass:  "prod-secret",
+				CertPath: "/etc/icinga2/ca.crt",
+				Timeout:  10 * time.Second,
+			},
+		},
+		{
+			name:    "explicit context with password file",
+			path:    configFile,
+			context: "staging",
+			want: &Config{
+				BaseURL:    "https://icinga-staging:5665/v1",
+				APIUser:    "staging",
+				APIPass:    "file-secret",
+				ServerName: "icinga-staging-master",
+				Timeout:    2500 * time.Millisecond,
+			},
+		},
+		{
+			name: "context and path from environment",
+			env:  map[string]string{EnvConfig: configFile, EnvContext: "staging"},
+			want: &Config{
+				BaseURL:    "https://icinga-staging:5665/v1",
+				APIUser:    "staging",
+				APIPass:    "file-secret",
+				ServerName: "icinga-staging-master",
+				Timeout:    2500 * time.Millisecond,
+			},
+		},
+		{
+			name: "environment overrides file",
+			path: configFile,
+			env: map[string]string{
+				EnvURL:                "https://icinga-env:5665/v1",
+				EnvPasswordFile:       passwordFile,
+				EnvInsecureSkipVerify: "true",
+			},
+			want: &Config{
+				BaseURL:            "https://icinga-env:5665/v1",
+				APIUser:            "root",
+				APIPass:            "file-secret",
+				CertPath:           "/etc/icinga2/ca.crt",
+				Timeout:            10 * time.Second,
+				InsecureSkipVerify: true,
+			},
+		},
+		{
+			name: "password takes precedence over password file",
+			env: map[string]string{
+				EnvURL:          "https://icinga-env:5665/v1",
+				EnvPassword:     "env-secret",
+				EnvPasswordFile: passwordFile,
+			},
+			want: &Config{
+				BaseURL: "https://icinga-env:5665/v1",
+				APIPass: "env-secret",
+			},
+		},
+		{
+			name: "single context in json",
+			path: jsonConfigFile,
+			want: &Config{
+				BaseURL: "https://icinga-json:5665/v1",
+				APIUser: "json",
+			},
+		},
+		{
+			name:    "unknown context",
+			path:    configFile,
+			context: "dev",
+			wantErr: true,
+		},
+		{
+			name:    "missing file",
+			path:    filepath.Join(dir, "missing.yaml"),
+			wantErr: true,
+		},
+		{
+			name:    "invalid timeout",
+			env:     map[string]string{EnvURL: "https://icinga-env:5665/v1", EnvTimeout: "soon"},
+			wantErr: true,
+		},
+		{
+			name:    "url without version",
+			env:     map[string]string{EnvURL: "https://icinga-env:5665"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, k := range []string{
+				EnvConfig, EnvContext, EnvURL, EnvUser, EnvPassword, EnvPasswordFile, EnvCAFile,
+				EnvClientCert, EnvClientKey, EnvServerName, EnvInsecureSkipVerify, EnvTimeout,
+			} {
+				t.Setenv(k, "")
+				os.Unsetenv(k)
+			}
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			got, err := LoadConfig(tt.path, tt.context)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			creds, err := got.credentials(context.Background())
+			if err != nil {
+				t.Fatalf("credentials() error = %v", err)
+			}
+			if creds.Username != tt.want.APIUser || creds.Password != tt.want.APIPass {
+				t.Errorf("LoadConfig() credentials = %+v, want %s:%s", creds, tt.want.APIUser, tt.want.APIPass)
+			}
+			if got.BaseURL != tt.want.BaseURL || got.CertPath != tt.want.CertPath ||
+				got.ServerName != tt.want.ServerName || got.Timeout != tt.want.Timeout ||
+				got.InsecureSkipVerify != tt.want.InsecureSkipVerify {
+				t.Errorf("LoadConfig() got = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{
+			name:   "valid",
+			config: Config{BaseURL: "https://icinga-server:5665/v1"},
+		},
+		{
+			name:    "empty base url",
+			config:  Config{},
+			wantErr: true,
+		},
+		{
+			name:    "missing version suffix",
+			config:  Config{BaseURL: "https://icinga-server:5665"},
+			wantErr: true,
+		},
+		{
+			name:    "trailing slash",
+			config:  Config{BaseURL: "https://icinga-server:5665/v1/"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid scheme",
+			config:  Config{BaseURL: "icinga-server:5665/v1"},
+			wantErr: true,
+		},
+		{
+			name:    "client cert without key",
+			config:  Config{BaseURL: "https://icinga-server:5665/v1", ClientCertPath: "client.crt"},
+			wantErr: true,
+		},
+		{
+			name:    "negative timeout",
+			config:  Config{BaseURL: "https://icinga-server:5665/v1", Timeout: -time.Second},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed writing %s: %v", path, err)
+	}
+}
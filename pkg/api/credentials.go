@@ -0,0 +1,222 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/puffitos/goicinga/internal/util"
+)
+
+// Credentials are the basic auth credentials for the icinga API.
+type Credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// empty reports whether no credentials are set, in which case no basic auth header is sent.
+func (c *Credentials) empty() bool {
+	return c == nil || (c.Username == "" && c.Password == "")
+}
+
+// CredentialProvider provides the credentials for the requests to the icinga API.
+// It is called for every request, so rotated credentials are picked up without a restart.
+// Implementations must be safe for concurrent use and should cache expensive lookups.
+type CredentialProvider interface {
+	Credentials(ctx context.Context) (*Credentials, error)
+}
+
+// staticCredentials always provides the same credentials.
+type staticCredentials struct {
+	creds Credentials
+}
+
+// StaticCredentials returns a provider for fixed credentials.
+func StaticCredentials(username, password string) CredentialProvider {
+	return &staticCredentials{creds: Credentials{Username: username, Password: password}}
+}
+
+// Credentials returns the fixed credentials.
+func (p *staticCredentials) Credentials(context.Context) (*Credentials, error) {
+	c := p.creds
+	return &c, nil
+}
+
+// fileCredentials reads the password from a file, which is read again whenever it changes.
+type fileCredentials struct {
+	username string
+	password *util.FileReloader[string]
+}
+
+// FileCredentials returns a provider, which reads the password from the file at the given path,
+// e.g. a mounted Kubernetes secret. The file is read again whenever it changes. Trailing
+// newlines are removed from the password. Returns an error if the file cannot be read.
+func FileCredentials(username, path string) (CredentialProvider, error) {
+	password, err := util.NewFileReloader(func() (string, error) {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read password file: %w", err)
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	}, path)
+	if err != nil {
+		return nil, err
+	}
+	return &fileCredentials{username: username, password: password}, nil
+}
+
+// Credentials returns the username and the current content of the password file.
+func (p *fileCredentials) Credentials(context.Context) (*Credentials, error) {
+	return &Credentials{Username: p.username, Password: p.password.Get()}, nil
+}
+
+// envCredentials reads the credentials from environment variables.
+type envCredentials struct {
+	userKey string
+	passKey string
+}
+
+// EnvCredentials returns a provider, which reads the credentials from the given
+// environment variables on every request.
+func EnvCredentials(userKey, passKey string) CredentialProvider {
+	return &envCredentials{userKey: userKey, passKey: passKey}
+}
+
+// Credentials returns the current values of the environment variables.
+func (p *envCredentials) Credentials(context.Context) (*Credentials, error) {
+	return &Credentials{Username: os.Getenv(p.userKey), Password: os.Getenv(p.passKey)}, nil
+}
+
+// ExecCredentialsOutput is the JSON object the command of an exec credential provider
+// must print to stdout.
+type ExecCredentialsOutput struct {
+	Credentials
+	// ExpiresAt is the time the credentials expire. If empty, the CacheTTL of the provider is used.
+	ExpiresAt time.Time `json:"expiresAt,omitempty"`
+}
+
+const defaultExecCredentialsTimeout = time.Minute
+
+// ExecCredentialsProvider runs a command to obtain the credentials, e.g. from a vault,
+// and caches them until they expire. Concurrent requests share a single run of the command,
+// which is not canceled if one of the callers gives up.
+type ExecCredentialsProvider struct {
+	// Command is the command to run.
+	Command string
+	// Args are the arguments passed to the command.
+	Args []string
+	// Env are additional environment variables passed to the command, in the form key=value.
+	Env []string
+	// CacheTTL is the time the credentials are cached if the command does not set an expiry.
+	// Zero disables caching.
+	CacheTTL time.Duration
+	// Timeout bounds the run of the command. Defaults to 1m.
+	Timeout time.Duration
+
+	mu      sync.Mutex
+	creds   *Credentials
+	expires time.Time
+	// running is the run of the command in progress, nil if there is none
+	running *execCredentialsRun
+}
+
+// execCredentialsRun is a run of the command of an ExecCredentialsProvider.
+// The results are set before done is closed.
+type execCredentialsRun struct {
+	done    chan struct{}
+	creds   *Credentials
+	expires time.Time
+	err     error
+}
+
+// ExecCredentials returns a provider, which runs the given command to obtain the credentials.
+// The command must print an ExecCredentialsOutput as JSON to stdout. Use an ExecCredentialsProvider
+// directly to set the environment, cache TTL or timeout of the command.
+func ExecCredentials(command string, args ...string) CredentialProvider {
+	return &ExecCredentialsProvider{Command: command, Args: args}
+}
+
+// Credentials returns the cached credentials, or runs the command if they expired.
+// Returns the error of the context if it ends before the command finished.
+func (p *ExecCredentialsProvider) Credentials(ctx context.Context) (*Credentials, error) {
+	p.mu.Lock()
+	if p.creds != nil && time.Now().Before(p.expires) {
+		c := *p.creds
+		p.mu.Unlock()
+		return &c, nil
+	}
+	run := p.running
+	if run == nil {
+		run = &execCredentialsRun{done: make(chan struct{})}
+		p.running = run
+		go p.run(run)
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-run.done:
+	}
+	if run.err != nil {
+		return nil, run.err
+	}
+	c := *run.creds
+	return &c, nil
+}
+
+// run runs the command outside the lock and caches the credentials it returns.
+func (p *ExecCredentialsProvider) run(run *execCredentialsRun) {
+	run.creds, run.expires, run.err = p.exec()
+
+	p.mu.Lock()
+	p.running = nil
+	if run.err == nil {
+		p.creds = run.creds
+		p.expires = run.expires
+		if p.expires.IsZero() {
+			p.expires = time.Now().Add(p.CacheTTL)
+		}
+	}
+	p.mu.Unlock()
+	close(run.done)
+}
+
+// exec runs the command and returns the credentials and expiry it printed.
+func (p *ExecCredentialsProvider) exec() (*Credentials, time.Time, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), durationOrDefault(p.Timeout, defaultExecCredentialsTimeout))
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, p.Command, p.Args...) //nolint:gosec
+	cmd.Env = append(os.Environ(), p.Env...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, time.Time{}, fmt.Errorf("credential command %s failed: %w: %s", p.Command, err, strings.TrimSpace(stderr.String()))
+	}
+
+	var out ExecCredentialsOutput
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		return nil, time.Time{}, fmt.Errorf("invalid output of credential command %s: %w", p.Command, err)
+	}
+	if out.Credentials.empty() {
+		return nil, time.Time{}, errors.New("credential command " + p.Command + " returned no credentials")
+	}
+	return &out.Credentials, out.ExpiresAt, nil
+}
+
+// credentials returns the credentials for a request, either from the configured
+// provider or from APIUser and APIPass.
+func (c *Config) credentials(ctx context.Context) (*Credentials, error) {
+	if c.Credentials != nil {
+		return c.Credentials.Credentials(ctx)
+	}
+	return &Credentials{Username: c.APIUser, Password: c.APIPass}, nil
+}
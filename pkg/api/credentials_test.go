@@ -0,0 +1,177 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+)
+
+func TestCredentialProviders(t *testing.T) {
+	dir := t.TempDir()
+	passwordFile := filepath.Join(dir, "password")
+	writeTestFile(t, passwordFile, "file-secret\n")
+	fileCreds, err := FileCredentials("file-user", passwordFile)
+	if err != nil {
+		t.Fatalf("FileCredentials() error = %v", err)
+	}
+	t.Setenv("TEST_ICINGA_USER", "env-user")
+	t.Setenv("TEST_ICINGA_PASS", "env-secret")
+
+	tests := []struct {
+		name     string
+		provider CredentialProvider
+		want     Credentials
+		wantErr  bool
+	}{
+		{
+			name:     "static",
+			provider: StaticCredentials("root", "secret"),
+			want:     Credentials{Username: "root", Password: "secret"},
+		},
+		{
+			name:     "file",
+			provider: fileCreds,
+			want:     Credentials{Username: "file-user", Password: "file-secret"},
+		},
+		{
+			name:     "environment",
+			provider: EnvCredentials("TEST_ICINGA_USER", "TEST_ICINGA_PASS"),
+			want:     Credentials{Username: "env-user", Password: "env-secret"},
+		},
+		{
+			name:     "exec",
+			provider: ExecCredentials("sh", "-c", `echo '{"username":"exec-user","password":"exec-secret"}'`),
+			want:     Credentials{Username: "exec-user", Password: "exec-secret"},
+		},
+		{
+			name:     "exec failure",
+			provider: ExecCredentials("sh", "-c", "exit 1"),
+			wantErr:  true,
+		},
+		{
+			name:     "exec invalid output",
+			provider: ExecCredentials("sh", "-c", "echo secret"),
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.provider.Credentials(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Credentials() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && *got != tt.want {
+				t.Errorf("Credentials() got = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileCredentials_Rotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "password")
+	writeTestFile(t, path, "old-secret")
+	p, err := FileCredentials("root", path)
+	if err != nil {
+		t.Fatalf("FileCredentials() error = %v", err)
+	}
+
+	c := newTestClient()
+	c.Config.Credentials = p
+	httpmock.ActivateNonDefault(c.Client)
+	defer httpmock.DeactivateAndReset()
+
+	var gotPass string
+	httpmock.RegisterResponder(http.MethodGet, c.Config.BaseURL+"/objects/hosts/test",
+		func(req *http.Request) (*http.Response, error) {
+			_, gotPass, _ = req.BasicAuth()
+			return httpmock.NewStringResponse(http.StatusOK, testHostQueryResult()), nil
+		})
+
+	for _, want := range []string{"old-secret", "new-secret"} {
+		if want == "new-secret" {
+			writeTestFile(t, path, want)
+			mtime := time.Now().Add(time.Minute)
+			if err := os.Chtimes(path, mtime, mtime); err != nil {
+				t.Fatalf("failed to change times of %s: %v", path, err)
+			}
+		}
+		if err := c.Get().Endpoint("objects").Type("hosts").Object("test").Call(context.Background()).Error(); err != nil {
+			t.Fatalf("Call() error = %v", err)
+		}
+		if gotPass != want {
+			t.Errorf("Call() sent password %q, want %q", gotPass, want)
+		}
+	}
+}
+
+func TestExecCredentialsProvider_Cache(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "calls")
+	p := &ExecCredentialsProvider{
+		Command:  "sh",
+		Args:     []string{"-c", `echo x >> "$COUNTER"; echo '{"username":"u","password":"p"}'`},
+		Env:      []string{"COUNTER=" + counter},
+		CacheTTL: time.Hour,
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := p.Credentials(context.Background()); err != nil {
+			t.Fatalf("Credentials() error = %v", err)
+		}
+	}
+	b, err := os.ReadFile(counter)
+	if err != nil {
+		t.Fatalf("failed reading counter: %v", err)
+	}
+	if calls := len(b) / 2; calls != 1 {
+		t.Errorf("command ran %d times, want 1", calls)
+	}
+}
+
+func TestExecCredentialsProvider_SingleFlight(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "calls")
+	p := &ExecCredentialsProvider{
+		Command:  "sh",
+		Args:     []string{"-c", `echo x >> "$COUNTER"; sleep 0.2; echo '{"username":"u","password":"p"}'`},
+		Env:      []string{"COUNTER=" + counter},
+		CacheTTL: time.Hour,
+	}
+
+	// a caller giving up does not wait for the command, nor cancel it for the others
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := p.Credentials(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Credentials() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if waited := time.Since(start); waited > 150*time.Millisecond {
+		t.Errorf("Credentials() returned after %v, want it to return with the context", waited)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, err := p.Credentials(context.Background())
+			if err != nil || c.Username != "u" {
+				t.Errorf("Credentials() = %v, %v", c, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	b, err := os.ReadFile(counter)
+	if err != nil {
+		t.Fatalf("failed reading counter: %v", err)
+	}
+	if calls := len(b) / 2; calls != 1 {
+		t.Errorf("command ran %d times, want 1", calls)
+	}
+}
@@ -0,0 +1,222 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+var unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// fieldPlan maps the JSON names of the fields of a struct type to the index paths of the fields.
+// Fields of embedded structs are flattened, fields of embedding structs take precedence.
+type fieldPlan map[string][]int
+
+// fieldPlans caches the plan of every struct type decoded so far.
+var fieldPlans sync.Map
+
+// planFor returns the cached plan of the given struct type.
+func planFor(t reflect.Type) fieldPlan {
+	if p, ok := fieldPlans.Load(t); ok {
+		return p.(fieldPlan)
+	}
+	p := fieldPlan{}
+	buildPlan(t, nil, p)
+	fieldPlans.Store(t, p)
+	return p
+}
+
+// buildPlan adds the fields of t, found at the index path at, to the plan. The shallow fields
+// are added first, then the embedded structs, so names in the plan are not overwritten by deeper fields.
+func buildPlan(t reflect.Type, at []int, p fieldPlan) {
+	var embedded []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && !implementsUnmarshaler(field.Type) {
+			embedded = append(embedded, field)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if _, ok := p[name]; ok {
+			continue
+		}
+		p[name] = append(at[:len(at):len(at)], i)
+	}
+	for _, field := range embedded {
+		buildPlan(field.Type, append(at[:len(at):len(at)], field.Index...), p)
+	}
+}
+
+// objectQueryResult is an ObjectQueryResult with undecoded attributes.
+type objectQueryResult struct {
+	Name  string                     `json:"name"`
+	Type  string                     `json:"type"`
+	Attrs map[string]json.RawMessage `json:"attrs"`
+}
+
+// decodeObject decodes the binary representation of an ObjectQueryResult into the object v points to.
+// The attributes are decoded into the fields with the same JSON name, the name and type of the
+// result take precedence over the attributes.
+func decodeObject(data []byte, v interface{}) error {
+	var oqr objectQueryResult
+	if err := json.Unmarshal(data, &oqr); err != nil {
+		return err
+	}
+
+	elem := reflect.ValueOf(v).Elem()
+	found, err := decodeFields(oqr.Attrs, elem)
+	if err != nil {
+		return err
+	}
+	if found == 0 {
+		return fmt.Errorf("no known fields found in Attrs map of ObjectQueryResult")
+	}
+
+	plan := planFor(elem.Type())
+	if idx, ok := plan["name"]; ok && oqr.Name != "" {
+		elem.FieldByIndex(idx).SetString(oqr.Name)
+	}
+	if idx, ok := plan["type"]; ok && oqr.Type != "" {
+		elem.FieldByIndex(idx).SetString(oqr.Type)
+	}
+	return nil
+}
+
+// decodeFields decodes the raw values into the fields of the struct v with the same JSON name.
+// Returns the number of known fields.
+func decodeFields(raw map[string]json.RawMessage, v reflect.Value) (int, error) {
+	plan := planFor(v.Type())
+	var found int
+	for name, msg := range raw {
+		idx, ok := plan[name]
+		if !ok {
+			continue
+		}
+		field := v.FieldByIndex(idx)
+		if !field.CanSet() {
+			continue
+		}
+		found++
+		if err := decodeValue(msg, field); err != nil {
+			return found, fmt.Errorf("failed to decode attribute %q: %w", name, err)
+		}
+	}
+	return found, nil
+}
+
+// decodeValue decodes the raw value into v. Numbers with a fractional part of zero are accepted
+// for integers, since the icinga API encodes all numbers as floating point numbers.
+func decodeValue(msg json.RawMessage, v reflect.Value) error {
+	if bytes.Equal(bytes.TrimSpace(msg), []byte("null")) {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	if implementsUnmarshaler(v.Type()) {
+		return json.Unmarshal(msg, v.Addr().Interface())
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f, err := decodeInteger(msg)
+		if err != nil {
+			return err
+		}
+		// the range is checked on the float, converting a value out of range is implementation-defined
+		limit := math.Ldexp(1, v.Type().Bits()-1)
+		if f < -limit || f >= limit {
+			return fmt.Errorf("number %s overflows %s", msg, v.Type())
+		}
+		v.SetInt(int64(f))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		f, err := decodeInteger(msg)
+		if err != nil {
+			return err
+		}
+		if f < 0 || f >= math.Ldexp(1, v.Type().Bits()) {
+			return fmt.Errorf("number %s overflows %s", msg, v.Type())
+		}
+		v.SetUint(uint64(f))
+	case reflect.Struct:
+		var raw map[string]json.RawMessage
+		if err := json.Unmarshal(msg, &raw); err != nil {
+			return err
+		}
+		_, err := decodeFields(raw, v)
+		return err
+	case reflect.Slice:
+		if isPlainType(v.Type().Elem()) {
+			return json.Unmarshal(msg, v.Addr().Interface())
+		}
+		var raw []json.RawMessage
+		if err := json.Unmarshal(msg, &raw); err != nil {
+			return err
+		}
+		s := reflect.MakeSlice(v.Type(), len(raw), len(raw))
+		for i := range raw {
+			if err := decodeValue(raw[i], s.Index(i)); err != nil {
+				return fmt.Errorf("index %d: %w", i, err)
+			}
+		}
+		v.Set(s)
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String || isPlainType(v.Type().Elem()) {
+			return json.Unmarshal(msg, v.Addr().Interface())
+		}
+		var raw map[string]json.RawMessage
+		if err := json.Unmarshal(msg, &raw); err != nil {
+			return err
+		}
+		m := reflect.MakeMapWithSize(v.Type(), len(raw))
+		for k, e := range raw {
+			value := reflect.New(v.Type().Elem()).Elem()
+			if err := decodeValue(e, value); err != nil {
+				return fmt.Errorf("key %q: %w", k, err)
+			}
+			m.SetMapIndex(reflect.ValueOf(k).Convert(v.Type().Key()), value)
+		}
+		v.Set(m)
+	default:
+		return json.Unmarshal(msg, v.Addr().Interface())
+	}
+	return nil
+}
+
+// decodeInteger decodes a finite number without a fractional part.
+func decodeInteger(msg json.RawMessage) (float64, error) {
+	var f float64
+	if err := json.Unmarshal(msg, &f); err != nil {
+		return 0, err
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) || f != math.Trunc(f) {
+		return 0, fmt.Errorf("number %s is not an integer", msg)
+	}
+	return f, nil
+}
+
+// isPlainType reports whether values of the type can be decoded by encoding/json directly,
+// because they cannot contain integers.
+func isPlainType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Interface, reflect.String, reflect.Bool, reflect.Float32, reflect.Float64:
+		return !implementsUnmarshaler(t)
+	default:
+		return false
+	}
+}
+
+func implementsUnmarshaler(t reflect.Type) bool {
+	return reflect.PointerTo(t).Implements(unmarshalerType)
+}
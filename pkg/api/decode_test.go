@@ -0,0 +1,176 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testServiceAttrs is a service as returned by the icinga API, with all numbers encoded as floats.
+const testServiceAttrs = `{
+	"name": "test-host!test-service",
+	"type": "Service",
+	"attrs": {
+		"name": "test-service",
+		"display_name": "test-service",
+		"host_name": "test-host",
+		"groups": ["linux", "web"],
+		"vars": {"os": "linux", "disks": {"/": {"warn": 80.0}}},
+		"state": 2.0,
+		"state_type": 1.0,
+		"max_check_attempts": 3.0,
+		"check_interval": 300.0,
+		"flapping_ignore_states": [1.0, 2.0],
+		"last_check": 1583020800.5,
+		"last_check_result": {
+			"exit_status": 2.0,
+			"state": 2.0,
+			"output": "CRITICAL - load is 12",
+			"performance_data": ["load1=12;5;10;0"],
+			"execution_start": 1583020800.0,
+			"execution_end": 1583020800.25,
+			"ttl": 0.0,
+			"vars_after": {"attempt": 1.0}
+		},
+		"unknown_attribute": {"ignored": true}
+	},
+	"joins": {},
+	"meta": {}
+}`
+
+func Test_decodeObject(t *testing.T) {
+	var got Service
+	if err := json.Unmarshal([]byte(testServiceAttrs), &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	want := Service{
+		HostName:    "test-host",
+		DisplayName: "test-service",
+		Groups:      []string{"linux", "web"},
+		State:       ServiceCritical,
+	}
+	want.Name = "test-host!test-service"
+	want.Type = "Service"
+	want.Vars = map[string]interface{}{"os": "linux", "disks": map[string]interface{}{"/": map[string]interface{}{"warn": 80.0}}}
+	want.StateType = StateTypeHard
+	want.MaxCheckAttempts = 3
+	want.CheckInterval = IcingaDuration(5 * time.Minute)
+	want.FlappingIgnoreStates = []int{1, 2}
+	want.LastCheck = NewIcingaTime(time.Date(2020, 3, 1, 0, 0, 0, 500000000, time.UTC))
+	want.LastCheckResult = CheckResult{
+		ExitStatus:      2,
+		State:           2,
+		Output:          "CRITICAL - load is 12",
+		PerformanceData: []string{"load1=12;5;10;0"},
+		ExecutionStart:  NewIcingaTime(time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)),
+		ExecutionEnd:    NewIcingaTime(time.Date(2020, 3, 1, 0, 0, 0, 250000000, time.UTC)),
+		VarsAfter:       map[string]interface{}{"attempt": 1.0},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unmarshal() got = %+v, want %+v", got, want)
+	}
+}
+
+func Test_decodeObject_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		attrs   string
+		wantErr string
+	}{
+		{
+			name:    "no known attributes",
+			attrs:   `{"foo": 1}`,
+			wantErr: "no known fields",
+		},
+		{
+			name:    "string for a time",
+			attrs:   `{"last_check": "yesterday"}`,
+			wantErr: `"last_check"`,
+		},
+		{
+			name:    "fraction for an integer",
+			attrs:   `{"max_check_attempts": 1.5}`,
+			wantErr: "not an integer",
+		},
+		{
+			name:    "number overflowing an integer",
+			attrs:   `{"max_check_attempts": 1e20}`,
+			wantErr: "overflows",
+		},
+		{
+			name:    "object for a slice",
+			attrs:   `{"groups": {"a": "b"}}`,
+			wantErr: `"groups"`,
+		},
+		{
+			name:    "invalid nested attribute",
+			attrs:   `{"last_check_result": {"exit_status": "two"}}`,
+			wantErr: `"exit_status"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s Service
+			err := json.Unmarshal([]byte(fmt.Sprintf(`{"name":"test","type":"Service","attrs":%s}`, tt.attrs)), &s)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Unmarshal() error = %v, want it to contain %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_decodeValue_IntegerRange(t *testing.T) {
+	tests := []struct {
+		name    string
+		v       interface{}
+		msg     string
+		wantErr bool
+	}{
+		{name: "int8 max", v: new(int8), msg: "127"},
+		{name: "int8 min", v: new(int8), msg: "-128"},
+		{name: "int8 above max", v: new(int8), msg: "128", wantErr: true},
+		{name: "int8 below min", v: new(int8), msg: "-129", wantErr: true},
+		{name: "int64 min", v: new(int64), msg: "-9223372036854775808"},
+		{name: "int64 above max", v: new(int64), msg: "9223372036854775808", wantErr: true},
+		{name: "int64 far above max", v: new(int64), msg: "1e20", wantErr: true},
+		{name: "uint8 max", v: new(uint8), msg: "255"},
+		{name: "uint8 above max", v: new(uint8), msg: "256", wantErr: true},
+		{name: "uint negative", v: new(uint), msg: "-1", wantErr: true},
+		{name: "uint64 above max", v: new(uint64), msg: "18446744073709551616", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := decodeValue(json.RawMessage(tt.msg), reflect.ValueOf(tt.v).Elem())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("decodeValue(%s) error = %v, wantErr %v", tt.msg, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_decodeObject_Null(t *testing.T) {
+	var s Service
+	data := `{"name":"test","type":"Service","attrs":{"display_name":"test","last_check_result":null,"groups":null}}`
+	if err := json.Unmarshal([]byte(data), &s); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if s.DisplayName != "test" || s.Groups != nil {
+		t.Errorf("Unmarshal() got = %+v", s)
+	}
+}
+
+func BenchmarkService_UnmarshalJSON(b *testing.B) {
+	data := []byte(testServiceAttrs)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var s Service
+		if err := json.Unmarshal(data, &s); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const defaultEndpointCoolDown = 30 * time.Second
+
+// endpointPool holds the base URLs of the icinga API and their health.
+// A single pool is shared by all clients created from the same Icinga client.
+type endpointPool struct {
+	mu         sync.Mutex
+	endpoints  []*endpoint
+	coolDown   time.Duration
+	roundRobin bool
+	// next is the index of the endpoint the next read starts with, if round-robin is enabled
+	next int
+}
+
+// endpoint is a single base URL of the icinga API.
+type endpoint struct {
+	url string
+	// unhealthyUntil is the end of the cool-down after the last failure
+	unhealthyUntil time.Time
+}
+
+// newEndpointPool creates the pool for the BaseURL and the additional endpoints of the config.
+func newEndpointPool(config *Config) *endpointPool {
+	p := &endpointPool{
+		coolDown:   durationOrDefault(config.EndpointCoolDown, defaultEndpointCoolDown),
+		roundRobin: config.RoundRobinReads,
+	}
+	seen := map[string]bool{}
+	for _, u := range append([]string{config.BaseURL}, config.Endpoints...) {
+		if u == "" || seen[u] {
+			continue
+		}
+		seen[u] = true
+		p.endpoints = append(p.endpoints, &endpoint{url: u})
+	}
+	return p
+}
+
+// order returns the endpoints in the order they should be tried: healthy endpoints first,
+// starting with the primary one, or with the next one in turn for reads if round-robin
+// is enabled. Unhealthy endpoints are still tried last, in case all of them failed.
+func (p *endpointPool) order(read bool) []*endpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	start := 0
+	if read && p.roundRobin && len(p.endpoints) > 0 {
+		start = p.next % len(p.endpoints)
+		p.next++
+	}
+
+	now := time.Now()
+	healthy := make([]*endpoint, 0, len(p.endpoints))
+	var unhealthy []*endpoint
+	for i := range p.endpoints {
+		e := p.endpoints[(start+i)%len(p.endpoints)]
+		if now.Before(e.unhealthyUntil) {
+			unhealthy = append(unhealthy, e)
+			continue
+		}
+		healthy = append(healthy, e)
+	}
+	return append(healthy, unhealthy...)
+}
+
+// markFailed puts the endpoint into cool-down.
+func (p *endpointPool) markFailed(e *endpoint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e.unhealthyUntil = time.Now().Add(p.coolDown)
+}
+
+// markHealthy ends the cool-down of the endpoint.
+func (p *endpointPool) markHealthy(e *endpoint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e.unhealthyUntil = time.Time{}
+}
+
+// shouldFailOver reports whether the result indicates an unavailable endpoint, so the request
+// should be sent to the next one. Icinga reports errors of single objects with a 500, so only
+// 502, 503 and 504 responses count as unavailability. Requests which are not safe to repeat
+// only fail over on a 503, or if the connection could not be established at all.
+// Errors caused by the end of the context of the caller never fail over.
+func shouldFailOver(ctx context.Context, res *Result, idempotent bool) bool {
+	if res.err == nil {
+		return false
+	}
+	if res.statusCode != 0 {
+		return isUnavailableStatus(res.statusCode) &&
+			(idempotent || res.statusCode == http.StatusServiceUnavailable)
+	}
+	if !res.transportErr || ctx.Err() != nil {
+		return false
+	}
+	if idempotent {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(res.err, &opErr) && opErr.Op == "dial"
+}
+
+// isUnavailableStatus reports whether the status code indicates that the icinga API is
+// unavailable, as opposed to an error of the request itself.
+func isUnavailableStatus(code int) bool {
+	switch code {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
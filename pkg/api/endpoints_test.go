@@ -0,0 +1,222 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/jarcoal/httpmock"
+)
+
+const (
+	testPrimaryURL   = "https://icinga-master-1:5665/v1"
+	testSecondaryURL = "https://icinga-master-2:5665/v1"
+)
+
+func TestRequest_Call_Failover(t *testing.T) {
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+
+	tests := []struct {
+		name          string
+		verb          string
+		primary       httpmock.Responder
+		wantSecondary bool
+		wantErr       bool
+	}{
+		{
+			name:          "primary available",
+			verb:          http.MethodGet,
+			primary:       httpmock.NewStringResponder(http.StatusOK, testHostQueryResult()),
+			wantSecondary: false,
+		},
+		{
+			name:          "primary reloading",
+			verb:          http.MethodGet,
+			primary:       httpmock.NewStringResponder(http.StatusServiceUnavailable, ""),
+			wantSecondary: true,
+		},
+		{
+			name:          "primary unreachable",
+			verb:          http.MethodGet,
+			primary:       httpmock.NewErrorResponder(dialErr),
+			wantSecondary: true,
+		},
+		{
+			name:          "object not found",
+			verb:          http.MethodGet,
+			primary:       httpmock.NewStringResponder(http.StatusNotFound, `{"error":404,"status":"No objects found."}`),
+			wantSecondary: false,
+			wantErr:       true,
+		},
+		{
+			name: "object error",
+			verb: http.MethodPost,
+			primary: httpmock.NewStringResponder(http.StatusInternalServerError,
+				`{"results":[{"code":500,"name":"test","status":"Attribute could not be set","type":"Host"}]}`),
+			wantSecondary: false,
+			wantErr:       true,
+		},
+		{
+			name:          "non idempotent request unreachable",
+			verb:          http.MethodPost,
+			primary:       httpmock.NewErrorResponder(dialErr),
+			wantSecondary: true,
+		},
+		{
+			name:          "non idempotent request connection reset",
+			verb:          http.MethodPost,
+			primary:       httpmock.NewErrorResponder(syscall.ECONNRESET),
+			wantSecondary: false,
+			wantErr:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestHAClient(t, false)
+			httpmock.ActivateNonDefault(c.Client)
+			defer httpmock.DeactivateAndReset()
+
+			httpmock.RegisterResponder(tt.verb, testPrimaryURL+"/objects/hosts/test", tt.primary)
+			httpmock.RegisterResponder(tt.verb, testSecondaryURL+"/objects/hosts/test",
+				httpmock.NewStringResponder(http.StatusOK, testHostQueryResult()))
+
+			err := c.Verb(tt.verb).Endpoint("objects").Type("hosts").Object("test").Call(context.Background()).Error()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Call() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			calls := httpmock.GetCallCountInfo()
+			if gotSecondary := calls[fmt.Sprintf("%s %s/objects/hosts/test", tt.verb, testSecondaryURL)] > 0; gotSecondary != tt.wantSecondary {
+				t.Errorf("Call() sent to secondary = %v, want %v", gotSecondary, tt.wantSecondary)
+			}
+		})
+	}
+}
+
+func TestRequest_Call_FailoverCoolDown(t *testing.T) {
+	c := newTestHAClient(t, false)
+	httpmock.ActivateNonDefault(c.Client)
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(http.MethodGet, testPrimaryURL+"/objects/hosts/test",
+		httpmock.NewStringResponder(http.StatusServiceUnavailable, ""))
+	httpmock.RegisterResponder(http.MethodGet, testSecondaryURL+"/objects/hosts/test",
+		httpmock.NewStringResponder(http.StatusOK, testHostQueryResult()))
+
+	for i := 0; i < 3; i++ {
+		if err := c.Get().Endpoint("objects").Type("hosts").Object("test").Call(context.Background()).Error(); err != nil {
+			t.Fatalf("Call() error = %v", err)
+		}
+	}
+
+	calls := httpmock.GetCallCountInfo()
+	if got := calls["GET "+testPrimaryURL+"/objects/hosts/test"]; got != 1 {
+		t.Errorf("primary called %d times during cool-down, want 1", got)
+	}
+	if got := calls["GET "+testSecondaryURL+"/objects/hosts/test"]; got != 3 {
+		t.Errorf("secondary called %d times, want 3", got)
+	}
+}
+
+func TestRequest_Call_RoundRobinReads(t *testing.T) {
+	c := newTestHAClient(t, true)
+	httpmock.ActivateNonDefault(c.Client)
+	defer httpmock.DeactivateAndReset()
+
+	for _, u := range []string{testPrimaryURL, testSecondaryURL} {
+		httpmock.RegisterResponder(http.MethodGet, u+"/objects/hosts/test",
+			httpmock.NewStringResponder(http.StatusOK, testHostQueryResult()))
+		httpmock.RegisterResponder(http.MethodPost, u+"/objects/hosts/test",
+			httpmock.NewStringResponder(http.StatusOK, `{"results":[]}`))
+	}
+
+	for i := 0; i < 4; i++ {
+		if err := c.Get().Endpoint("objects").Type("hosts").Object("test").Call(context.Background()).Error(); err != nil {
+			t.Fatalf("Call() error = %v", err)
+		}
+		if err := c.Post().Endpoint("objects").Type("hosts").Object("test").Call(context.Background()).Error(); err != nil {
+			t.Fatalf("Call() error = %v", err)
+		}
+	}
+
+	calls := httpmock.GetCallCountInfo()
+	for _, u := range []string{testPrimaryURL, testSecondaryURL} {
+		if got := calls["GET "+u+"/objects/hosts/test"]; got != 2 {
+			t.Errorf("%s received %d reads, want 2", u, got)
+		}
+	}
+	if got := calls["POST "+testPrimaryURL+"/objects/hosts/test"]; got != 4 {
+		t.Errorf("primary received %d writes, want 4", got)
+	}
+}
+
+func TestRequest_url(t *testing.T) {
+	c := newTestClient()
+	tests := []struct {
+		name string
+		req  *Request
+		want string
+	}{
+		{
+			name: "object",
+			req:  c.Get().Endpoint("objects").Type("hosts").Object("test"),
+			want: c.Config.BaseURL + "/objects/hosts/test",
+		},
+		{
+			name: "action",
+			req:  c.Post().Endpoint("actions").Object("process-check-result"),
+			want: c.Config.BaseURL + "/actions/process-check-result",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.req.url(c.Config.BaseURL); got != tt.want {
+				t.Errorf("url() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// newTestHAClient returns a client for two icinga masters.
+func newTestHAClient(t *testing.T, roundRobin bool) *Icinga {
+	t.Helper()
+	l := logr.Discard()
+	c, err := New(&Config{
+		BaseURL:          testPrimaryURL,
+		Endpoints:        []string{testSecondaryURL},
+		EndpointCoolDown: time.Minute,
+		RoundRobinReads:  roundRobin,
+	}, &l)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return c
+}
+
+func TestRequest_Call_FailoverTimeout(t *testing.T) {
+	var primaryCalls, secondaryCalls int32
+	primary := newHangingServer(t, 1, &primaryCalls)
+	secondary := newHangingServer(t, 0, &secondaryCalls)
+	l := logr.Discard()
+	c, err := New(&Config{
+		BaseURL:   primary.URL + "/v1",
+		Endpoints: []string{secondary.URL + "/v1"},
+		Timeout:   50 * time.Millisecond,
+	}, &l)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if err := c.Get().Endpoint("objects").Type("hosts").Call(context.Background()).Error(); err != nil {
+		t.Fatalf("Call() error = %v, want the request to fail over", err)
+	}
+	if p, s := atomic.LoadInt32(&primaryCalls), atomic.LoadInt32(&secondaryCalls); p != 1 || s != 1 {
+		t.Errorf("Call() sent %d requests to the primary and %d to the secondary, want 1 each", p, s)
+	}
+}
@@ -2,7 +2,10 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
 )
 
 // IcingaError is the error returned by the icinga API
@@ -23,6 +26,8 @@ type IcingaInternalError struct {
 	Status string `json:"status"`
 	// Type is the type of the object that caused the error
 	Type string `json:"type"`
+	// Errors are the detailed error messages returned by the icinga API, if any
+	Errors []string `json:"errors,omitempty"`
 }
 
 // Error returns the error message
@@ -35,41 +40,66 @@ func (e *IcingaError) Error() string {
 	return fmt.Sprintf("IcingaError code: %d, error msg: %s", e.Err, e.Status)
 }
 
-// WrapError wraps the error returned by the icinga API
+// ResultErrors holds the errors of the objects an operation failed for, if the icinga API
+// returned more than one result, e.g. for filter based actions or cascading deletes.
+// The results of the objects the operation succeeded for are available via Result.Succeeded.
+type ResultErrors struct {
+	// StatusCode is the HTTP status code of the response, 0 if unknown
+	StatusCode int
+	// Errors holds one error per failed object
+	Errors []*IcingaInternalError
+}
+
+// Error returns the error messages of all failed objects
+func (e *ResultErrors) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("operation failed for %d objects: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the errors of the failed objects, for errors.Is and errors.As
+func (e *ResultErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// WrapError wraps the error returned by the icinga API.
+// A single result is returned as IcingaInternalError, multiple results as ResultErrors.
 func WrapError(body []byte) error {
-	var d map[string]interface{}
+	var d map[string]json.RawMessage
 	err := json.Unmarshal(body, &d)
 	if err != nil {
 		return err
 	}
 
 	// case 1: icinga internal error
-	if _, ok := d["results"]; ok {
-		results := d["results"].([]interface{})
-		if len(results) == 0 || len(results) > 1 {
-			return fmt.Errorf("invalid number of results in error response")
-		}
-
-		err := results[0].(map[string]interface{})
-		// sanity check: check that all expected fields are present
-		if _, ok := err["code"]; !ok {
-			return fmt.Errorf("invalid error response")
+	if raw, ok := d["results"]; ok {
+		var results []ObjectResult
+		if err := json.Unmarshal(raw, &results); err != nil {
+			return fmt.Errorf("invalid error response: %w", err)
 		}
-		if _, ok := err["name"]; !ok {
-			return fmt.Errorf("invalid error response")
+		if len(results) == 0 {
+			return fmt.Errorf("invalid number of results in error response")
 		}
-		if _, ok := err["status"]; !ok {
-			return fmt.Errorf("invalid error response")
+		if len(results) == 1 {
+			return results[0].toError()
 		}
-		if _, ok := err["type"]; !ok {
-			return fmt.Errorf("invalid error response")
+
+		errs := &ResultErrors{}
+		for i := range results {
+			if !results[i].Succeeded() {
+				errs.Errors = append(errs.Errors, results[i].toError())
+			}
 		}
-		return &IcingaInternalError{
-			Code:   int(err["code"].(float64)),
-			Name:   err["name"].(string),
-			Status: err["status"].(string),
-			Type:   err["type"].(string),
+		if len(errs.Errors) == 0 {
+			return fmt.Errorf("invalid error response: all results succeeded")
 		}
+		return errs
 	}
 
 	// case 2: icinga error
@@ -81,6 +111,125 @@ func WrapError(body []byte) error {
 	return &result
 }
 
+// wrapStatusError wraps the error response of the icinga API with the given status code.
+// If the body is not a valid icinga error, e.g. the HTML page of a proxy or of a failed
+// authentication, an IcingaError with the status code is returned instead.
+func wrapStatusError(code int, body []byte) error {
+	err := WrapError(body)
+	var icErr *IcingaError
+	if errors.As(err, &icErr) {
+		if icErr.Err == 0 {
+			icErr.Err = code
+		}
+		if icErr.Status == "" {
+			icErr.Status = http.StatusText(code)
+		}
+		return icErr
+	}
+	var resErrs *ResultErrors
+	if errors.As(err, &resErrs) {
+		resErrs.StatusCode = code
+		return err
+	}
+	var intErr *IcingaInternalError
+	if errors.As(err, &intErr) {
+		return err
+	}
+	return &IcingaError{Err: code, Status: http.StatusText(code)}
+}
+
+// StatusCode returns the status code of the error in the chain of err: the status of the response
+// for ResultErrors, or the code of an IcingaError or IcingaInternalError. Returns 0 if there is none.
+func StatusCode(err error) int {
+	var resErrs *ResultErrors
+	if errors.As(err, &resErrs) && resErrs.StatusCode != 0 {
+		return resErrs.StatusCode
+	}
+	var icErr *IcingaError
+	if errors.As(err, &icErr) {
+		return icErr.Err
+	}
+	var intErr *IcingaInternalError
+	if errors.As(err, &intErr) {
+		return intErr.Code
+	}
+	return 0
+}
+
+// hasStatus reports whether the status of the response, or the code of any failed object,
+// in the chain of err matches.
+func hasStatus(err error, match func(code int) bool) bool {
+	if code := StatusCode(err); code != 0 && match(code) {
+		return true
+	}
+	for _, intErr := range internalErrors(err) {
+		if match(intErr.Code) {
+			return true
+		}
+	}
+	return false
+}
+
+// internalErrors returns the errors of the failed objects in the chain of err.
+func internalErrors(err error) []*IcingaInternalError {
+	var resErrs *ResultErrors
+	if errors.As(err, &resErrs) {
+		return resErrs.Errors
+	}
+	var intErr *IcingaInternalError
+	if errors.As(err, &intErr) {
+		return []*IcingaInternalError{intErr}
+	}
+	return nil
+}
+
+func isStatus(status int) func(code int) bool {
+	return func(code int) bool { return code == status }
+}
+
+// IsNotFound reports whether the error was returned because the object does not exist.
+func IsNotFound(err error) bool {
+	return hasStatus(err, isStatus(http.StatusNotFound))
+}
+
+// IsAlreadyExists reports whether the error was returned because the object already exists.
+// Icinga reports existing objects on creation with a 500 and a message, which is checked as well.
+func IsAlreadyExists(err error) bool {
+	if hasStatus(err, isStatus(http.StatusConflict)) {
+		return true
+	}
+	for _, intErr := range internalErrors(err) {
+		for _, msg := range append([]string{intErr.Status}, intErr.Errors...) {
+			if strings.Contains(strings.ToLower(msg), "already exists") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsForbidden reports whether the error was returned because the API user lacks the permission.
+func IsForbidden(err error) bool {
+	return hasStatus(err, isStatus(http.StatusForbidden))
+}
+
+// IsUnauthorized reports whether the error was returned because the authentication failed.
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, isStatus(http.StatusUnauthorized))
+}
+
+// IsBadRequest reports whether the error was returned because the request was invalid.
+func IsBadRequest(err error) bool {
+	return hasStatus(err, isStatus(http.StatusBadRequest))
+}
+
+// IsServerError reports whether the error was returned with a 5xx status code.
+func IsServerError(err error) bool {
+	return hasStatus(err, func(code int) bool {
+		return code >= http.StatusInternalServerError && code < 600
+	})
+}
+
 type NoIdentifierError struct {
 	// The type of the object
 	Object string
@@ -1,6 +1,9 @@
 package api
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
 	"testing"
 )
 
@@ -73,3 +76,224 @@ func Test_WrapError_Multiple(t *testing.T) {
 		})
 	}
 }
+
+func Test_ErrorPredicates(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		check func(error) bool
+		want  bool
+	}{
+		{
+			name:  "not found",
+			err:   &IcingaError{Err: 404, Status: "No objects found."},
+			check: IsNotFound,
+			want:  true,
+		},
+		{
+			name:  "wrapped not found",
+			err:   fmt.Errorf("failed to get host: %w", &IcingaError{Err: 404}),
+			check: IsNotFound,
+			want:  true,
+		},
+		{
+			name:  "internal error not found",
+			err:   &IcingaInternalError{Code: 404, Name: "test", Type: "Host"},
+			check: IsNotFound,
+			want:  true,
+		},
+		{
+			name:  "other error",
+			err:   errors.New("connection refused"),
+			check: IsNotFound,
+			want:  false,
+		},
+		{
+			name:  "nil error",
+			err:   nil,
+			check: IsServerError,
+			want:  false,
+		},
+		{
+			name: "already exists",
+			err: &IcingaInternalError{
+				Code:   500,
+				Status: "Object could not be created.",
+				Errors: []string{"Object 'test' of type 'Host' re-defined: Object already exists."},
+			},
+			check: IsAlreadyExists,
+			want:  true,
+		},
+		{
+			name:  "conflict",
+			err:   &IcingaError{Err: 409},
+			check: IsAlreadyExists,
+			want:  true,
+		},
+		{
+			name:  "other internal error",
+			err:   &IcingaInternalError{Code: 500, Status: "Attribute 'statis' could not be set."},
+			check: IsAlreadyExists,
+			want:  false,
+		},
+		{
+			name:  "forbidden",
+			err:   &IcingaError{Err: 403},
+			check: IsForbidden,
+			want:  true,
+		},
+		{
+			name:  "unauthorized",
+			err:   &IcingaError{Err: 401},
+			check: IsUnauthorized,
+			want:  true,
+		},
+		{
+			name:  "bad request",
+			err:   &IcingaError{Err: 400},
+			check: IsBadRequest,
+			want:  true,
+		},
+		{
+			name:  "server error",
+			err:   &IcingaInternalError{Code: 503},
+			check: IsServerError,
+			want:  true,
+		},
+		{
+			name:  "client error is no server error",
+			err:   &IcingaError{Err: 404},
+			check: IsServerError,
+			want:  false,
+		},
+		{
+			name:  "result errors with not found status",
+			err:   &ResultErrors{StatusCode: 404, Errors: []*IcingaInternalError{{Code: 500}, {Code: 500}}},
+			check: IsNotFound,
+			want:  true,
+		},
+		{
+			name:  "result errors with not found object",
+			err:   &ResultErrors{StatusCode: 500, Errors: []*IcingaInternalError{{Code: 500}, {Code: 404}}},
+			check: IsNotFound,
+			want:  true,
+		},
+		{
+			name: "result errors with existing object",
+			err: &ResultErrors{StatusCode: 500, Errors: []*IcingaInternalError{
+				{Code: 500, Status: "Attribute 'statis' could not be set."},
+				{Code: 500, Errors: []string{"Object already exists."}},
+			}},
+			check: IsAlreadyExists,
+			want:  true,
+		},
+		{
+			name:  "result errors with server error status",
+			err:   fmt.Errorf("failed: %w", &ResultErrors{StatusCode: 500, Errors: []*IcingaInternalError{{Code: 404}, {Code: 404}}}),
+			check: IsServerError,
+			want:  true,
+		},
+		{
+			name:  "result errors without matching code",
+			err:   &ResultErrors{StatusCode: 500, Errors: []*IcingaInternalError{{Code: 500}, {Code: 500}}},
+			check: IsNotFound,
+			want:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.check(tt.err); got != tt.want {
+				t.Errorf("check(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_WrapError_Errors(t *testing.T) {
+	data := `{"results":[{"code":500,"errors":["Object 'test' already exists."],"name":"test","status":"Object could not be created.","type":"Host"}]}`
+	var e *IcingaInternalError
+	if !errors.As(WrapError([]byte(data)), &e) {
+		t.Fatalf("expected IcingaInternalError")
+	}
+	if len(e.Errors) != 1 || e.Errors[0] != "Object 'test' already exists." {
+		t.Errorf("Errors = %v", e.Errors)
+	}
+}
+
+func Test_WrapError_ResultErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       string
+		wantErrors int
+		wantErr    bool
+	}{
+		{
+			name: "filter action with failed objects",
+			data: `{"results":[` +
+				`{"code":200.0,"name":"host1!load","status":"Successfully processed check result for object 'host1!load'.","type":"Service"},` +
+				`{"code":404.0,"name":"host2!load","status":"Object not found.","type":"Service"},` +
+				`{"code":500.0,"name":"host3!load","status":"Failed to process check result.","type":"Service"}]}`,
+			wantErrors: 2,
+		},
+		{
+			name:    "empty results",
+			data:    `{"results":[]}`,
+			wantErr: true,
+		},
+		{
+			name:    "result without code",
+			data:    `{"results":[{"status":"failed"},{"status":"failed"}]}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := WrapError([]byte(tt.data))
+			var errs *ResultErrors
+			if !errors.As(err, &errs) {
+				if !tt.wantErr {
+					t.Fatalf("WrapError() = %v, want ResultErrors", err)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatalf("WrapError() = %v, want invalid response error", err)
+			}
+			if len(errs.Errors) != tt.wantErrors {
+				t.Errorf("got %d errors, want %d", len(errs.Errors), tt.wantErrors)
+			}
+
+			// the errors of the single objects are reachable through errors.As
+			var internal *IcingaInternalError
+			if !errors.As(err, &internal) || internal.Name != "host2!load" {
+				t.Errorf("errors.As() = %v, want the first failed object", internal)
+			}
+			if !IsNotFound(err) {
+				t.Errorf("IsNotFound() = false, want true")
+			}
+		})
+	}
+}
+
+func Test_wrapStatusError_ResultErrors(t *testing.T) {
+	data := `{"results":[` +
+		`{"code":404.0,"name":"host1!load","status":"Object not found.","type":"Service"},` +
+		`{"code":404.0,"name":"host2!load","status":"Object not found.","type":"Service"}]}`
+	err := wrapStatusError(http.StatusInternalServerError, []byte(data))
+
+	var errs *ResultErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("wrapStatusError() = %v, want ResultErrors", err)
+	}
+	if errs.StatusCode != http.StatusInternalServerError {
+		t.Errorf("StatusCode = %d, want %d", errs.StatusCode, http.StatusInternalServerError)
+	}
+	if got := StatusCode(err); got != http.StatusInternalServerError {
+		t.Errorf("StatusCode(err) = %d, want %d", got, http.StatusInternalServerError)
+	}
+	if !IsServerError(err) || !IsNotFound(err) {
+		t.Errorf("IsServerError() = %v, IsNotFound() = %v, want both true", IsServerError(err), IsNotFound(err))
+	}
+}
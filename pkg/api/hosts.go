@@ -2,13 +2,7 @@ package api
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"reflect"
-	"strings"
-	"time"
-
-	"github.com/go-logr/logr"
 )
 
 type Host struct {
@@ -20,74 +14,18 @@ type Host struct {
 	// The host’s IPv6 address. Available as command runtime macro $address6$ if set.
 	Address6 string `json:"address_6,omitempty"`
 	// A list of host groups this host belongs to.
-	Groups        []string  `json:"groups,omitempty"`
-	LastHardState int       `json:"last_hard_state,omitempty"`
-	LastState     int       `json:"last_state,omitempty"`
-	LastStateDown time.Time `json:"last_state_down,omitempty"`
-	LastStateUp   time.Time `json:"last_state_up,omitempty"`
-	State         HostState `json:"state,omitempty"`
+	Groups        []string   `json:"groups,omitempty"`
+	LastHardState int        `json:"last_hard_state,omitempty"`
+	LastState     int        `json:"last_state,omitempty"`
+	LastStateDown IcingaTime `json:"last_state_down"`
+	LastStateUp   IcingaTime `json:"last_state_up"`
+	State         HostState  `json:"state,omitempty"`
 }
 
 // UnmarshalJSON implements the json.Unmarshaler interface.
 // The data is expected to be the binary representation of a ObjectQueryResult.
 func (h *Host) UnmarshalJSON(data []byte) error {
-	var oqr ObjectQueryResult
-	if err := json.Unmarshal(data, &oqr); err != nil {
-		return err
-	}
-
-	// iterate over all fields of the Host struct and set them to their
-	// corresponding fields found in the oqr.Attrs map.
-	elem := reflect.ValueOf(h).Elem()
-	fields := getStructFields(elem.Type())
-	var found int
-	for _, v := range fields {
-		n := strings.Split(v.Tag.Get("json"), ",")[0]
-		if oqrv, ok := oqr.Attrs[n]; ok {
-			found++
-			typ := v.Type
-			// The State field is of type HostState, but the API returns an int.
-			// This makes sure that the CheckResult.State field is of type HostState.
-			if v.Name == "State" {
-				typ = reflect.TypeOf(HostState(0))
-			}
-			if typ == reflect.TypeOf(time.Time{}) {
-				seconds := int64(oqrv.(float64))
-				nanoseconds := int64((oqrv.(float64) - float64(seconds)) * Ms)
-				t := time.Unix(seconds, nanoseconds).UTC()
-				elem.FieldByName(v.Name).Set(reflect.ValueOf(t))
-				continue
-			}
-			value := reflect.ValueOf(oqrv).Convert(typ)
-			elem.FieldByName(v.Name).Set(value)
-		}
-	}
-
-	if found == 0 {
-		return fmt.Errorf("no known fields found in Attrs map of ObjectQueryResult")
-	}
-
-	type Alias Host
-	return json.Unmarshal(data, (*Alias)(h))
-}
-
-func getStructFields(t reflect.Type) []reflect.StructField {
-	var fields []reflect.StructField
-
-	// Iterate over the fields of the struct.
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
-
-		// Structs must be handled recursively.
-		if field.Type.Kind() == reflect.Struct && field.Type != reflect.TypeOf(time.Time{}) {
-			nestedFields := getStructFields(field.Type)
-			fields = append(fields, nestedFields...)
-		} else {
-			fields = append(fields, field)
-		}
-	}
-
-	return fields
+	return decodeObject(data, h)
 }
 
 type HostState int
@@ -99,9 +37,13 @@ const (
 
 // Hosts is the interface for interacting with Icinga hosts.
 type Hosts interface {
-	Get(ctx context.Context, name string) (*Host, error)
+	Get(ctx context.Context, name string, attrs ...string) (*Host, error)
+	List(ctx context.Context, query *ObjectQuery) ([]*Host, error)
+	Stream(ctx context.Context, query *ObjectQuery, fn func(host *Host) error) error
 	Create(ctx context.Context, host *Host) error
 	Update(ctx context.Context, host *Host) error
+	UpdateAttrs(ctx context.Context, name string, attrs map[string]interface{}) error
+	Patch(ctx context.Context, old, new *Host) error
 	Delete(ctx context.Context, name string, cascade bool) error
 }
 
@@ -111,54 +53,124 @@ type hosts struct {
 }
 
 // newHostsClient returns a new Hosts client.
-func newHostsClient(cfg *Config, log *logr.Logger) *hosts {
-	l := log.WithName("hosts")
-	return &hosts{ic: New(cfg, &l)}
+func newHostsClient(ic *Icinga) *hosts {
+	return &hosts{ic: ic.named("hosts")}
 }
 
-// Get returns the host with the given name, or nil if it doesn't exist.
-func (c *hosts) Get(ctx context.Context, name string) (*Host, error) {
+// Get returns the host with the given name, or nil and an error satisfying IsNotFound
+// if it doesn't exist.
+// If attrs are given, only those attributes are fetched and the others are left empty,
+// see ObjectAttrs.IsLoaded.
+func (c *hosts) Get(ctx context.Context, name string, attrs ...string) (*Host, error) {
 	if name == "" {
 		return nil, &NoIdentifierError{Object: "host"}
 	}
-	var res Host
-	err := c.ic.
-		Get().
+	r := c.ic.Get().
 		Endpoint("objects").
 		Type("hosts").
-		Object(name).
-		Call(ctx).
-		Into(&res)
-	return &res, err
+		Object(name)
+	if len(attrs) > 0 {
+		r = r.Body(&ObjectQuery{Attrs: attrs})
+	}
+	res, err := getObject[Host](ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	res.setLoaded(attrs)
+	return res, nil
 }
 
+// Update modifies the writable attributes of the given host, which are not zero.
+// Runtime state like the last check result and attributes which can only be set on creation,
+// like the zone and groups, are never sent. Use UpdateAttrs or Patch to reset attributes to
+// their zero value.
 func (c *hosts) Update(ctx context.Context, host *Host) error {
 	if host == nil {
 		return fmt.Errorf("host cannot be nil")
 	}
+	if host.Name == "" {
+		return &NoIdentifierError{Object: "host"}
+	}
+	return updateAttrs(ctx, c.ic, "hosts", host.Name, objectAttrs(host, writableAttrs, true))
+}
+
+// UpdateAttrs modifies the given attributes of the host with the given name.
+// The attributes are keyed by their JSON name, single custom variables can be set as "vars.<name>".
+func (c *hosts) UpdateAttrs(ctx context.Context, name string, attrs map[string]interface{}) error {
+	if name == "" {
+		return &NoIdentifierError{Object: "host"}
+	}
+	if err := validateAttrs(attrs); err != nil {
+		return err
+	}
+	return updateAttrs(ctx, c.ic, "hosts", name, attrs)
+}
+
+// Patch modifies the writable attributes which differ between the old and the new host.
+// Changes of attributes which can only be set on creation, like the zone and groups, are not sent.
+// No request is sent if nothing changed.
+func (c *hosts) Patch(ctx context.Context, old, new *Host) error {
+	if old == nil || new == nil {
+		return fmt.Errorf("host cannot be nil")
+	}
+	if new.Name == "" {
+		return &NoIdentifierError{Object: "host"}
+	}
+	attrs := diffAttrs(old, new)
+	if len(attrs) == 0 {
+		return nil
+	}
+	return updateAttrs(ctx, c.ic, "hosts", new.Name, attrs)
+}
 
-	b := &UpdateObjectRequest[Host]{
-		Attrs: *host,
+// List returns all hosts matching the query, or all hosts if the query is nil.
+// The results are decoded while they are received, use Stream to process them one by one
+// without holding all of them in memory.
+func (c *hosts) List(ctx context.Context, query *ObjectQuery) ([]*Host, error) {
+	var res []*Host
+	err := c.Stream(ctx, query, func(host *Host) error {
+		res = append(res, host)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return res, nil
+}
 
-	res := c.ic.Post().
+// Stream decodes the hosts matching the query one by one and passes them to fn,
+// or all hosts if the query is nil. Memory use is bounded by the size of a single host.
+// Streaming stops at the first error returned by fn, which is returned.
+// If the query selects attributes, only those are loaded, see ObjectAttrs.IsLoaded.
+func (c *hosts) Stream(ctx context.Context, query *ObjectQuery, fn func(host *Host) error) error {
+	if fn == nil {
+		return fmt.Errorf("callback cannot be nil")
+	}
+	r := c.ic.Get().
 		Endpoint("objects").
-		Type("hosts").
-		Object(host.Name).
-		Body(b).
-		Call(ctx)
-	return res.Error()
+		Type("hosts")
+	var attrs []string
+	if query != nil {
+		r = r.Body(query)
+		attrs = query.Attrs
+	}
+	return streamObjects(ctx, r, func(host *Host) error {
+		host.setLoaded(attrs)
+		return fn(host)
+	})
 }
 
-// Create creates the given host.
+// Create creates the given host with its templates and the attributes which are set, including
+// those which cannot be modified afterwards, like the zone and groups.
 func (c *hosts) Create(ctx context.Context, host *Host) error {
 	if host == nil {
 		return fmt.Errorf("host cannot be nil")
 	}
 
-	b := &CreateObjectRequest[CheckableAttrs]{
+	// only the set attributes are sent, so the defaults of icinga and the templates apply
+	b := &CreateObjectRequest[map[string]interface{}]{
 		Templates: host.Templates,
-		Attrs:     host.CheckableAttrs,
+		Attrs:     objectAttrs(host, creationAttrs, true),
 	}
 
 	res := c.ic.Put().
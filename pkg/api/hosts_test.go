@@ -3,10 +3,11 @@ package api
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"reflect"
+	"strings"
 	"testing"
-	"time"
 
 	"github.com/jarcoal/httpmock"
 )
@@ -41,9 +42,17 @@ func Test_hosts_Get(t *testing.T) {
 			hostName: "test",
 			want:     testHost(),
 			wantCode: http.StatusOK,
-			wantBody: testHostQueryResult(),
+			wantBody: `{"results":[` + testHostQueryResult() + `]}`,
 			wantErr:  false,
 		},
+		{
+			name:     "no results",
+			hostName: "test",
+			want:     nil,
+			wantCode: http.StatusOK,
+			wantBody: `{"results":[]}`,
+			wantErr:  true,
+		},
 	}
 
 	c := hosts{
@@ -63,6 +72,9 @@ func Test_hosts_Get(t *testing.T) {
 			if (err != nil) != tt.wantErr {
 				t.Fatalf("Get() error = %v, wantErr %v", err, tt.wantErr)
 			}
+			if tt.wantCode != 0 && tt.want == nil && !IsNotFound(err) {
+				t.Errorf("Get() error = %v, want a not found error", err)
+			}
 			if err == nil && !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("Get() got = %v, wantFields %v", got, tt.want)
 			}
@@ -132,6 +144,30 @@ func Test_hosts_Create(t *testing.T) {
 	}
 }
 
+func Test_hosts_Create_body(t *testing.T) {
+	c := hosts{ic: newTestClient()}
+	httpmock.ActivateNonDefault(c.ic.Client)
+	defer httpmock.DeactivateAndReset()
+
+	var body string
+	httpmock.RegisterResponder(http.MethodPut, c.ic.Config.BaseURL+"/objects/hosts/web1",
+		func(req *http.Request) (*http.Response, error) {
+			b, err := io.ReadAll(req.Body)
+			body = string(b)
+			return httpmock.NewStringResponse(http.StatusOK, `{"results":[{"code":200.0}]}`), err
+		})
+
+	host := &Host{Address: "10.0.0.1"}
+	host.Name = "web1"
+	host.Templates = []string{"generic-host"}
+	if err := c.Create(context.Background(), host); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if want := `{"templates":["generic-host"],"attrs":{"address":"10.0.0.1"}}`; strings.TrimSpace(body) != want {
+		t.Errorf("Create() sent body %s, want %s", body, want)
+	}
+}
+
 func Test_hosts_Update(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -253,7 +289,7 @@ func Test_hosts_Delete(t *testing.T) {
 	}
 }
 
-func Test_getStructFields(t *testing.T) {
+func Test_planFor(t *testing.T) {
 	type args struct {
 		t reflect.Type
 	}
@@ -274,68 +310,76 @@ func Test_getStructFields(t *testing.T) {
 		Test string
 		doubleEmbed
 	}
+	type structWithShadowing struct {
+		embed
+		EmbeddedTest int
+		Nested       embed `json:"nested"`
+	}
 
 	tests := []struct {
 		name       string
 		args       args
-		wantFields []string
+		wantFields map[string][]int
 	}{
 		{
 			name:       "empty",
 			args:       args{t: reflect.TypeOf(struct{}{})},
-			wantFields: nil,
+			wantFields: map[string][]int{},
 		},
 		{
 			name: "struct with embed",
 			args: args{t: reflect.TypeOf(structWithEmbed{})},
-			wantFields: []string{
-				"Test",
-				"EmbeddedTest",
+			wantFields: map[string][]int{
+				"Test":         {0},
+				"EmbeddedTest": {1, 0},
 			},
 		},
 		{
 			name: "double embed",
 			args: args{t: reflect.TypeOf(structWithDoubleEmbed{})},
-			wantFields: []string{
-				"Test",
-				"EmbeddedTest",
-				"DoubleEmbeddedTest",
+			wantFields: map[string][]int{
+				"Test":               {0},
+				"EmbeddedTest":       {1, 0, 0},
+				"DoubleEmbeddedTest": {1, 1},
+			},
+		},
+		{
+			name: "shallow fields take precedence, nested structs are not flattened",
+			args: args{t: reflect.TypeOf(structWithShadowing{})},
+			wantFields: map[string][]int{
+				"EmbeddedTest": {1},
+				"nested":       {2},
 			},
 		},
 		{
 			name: "host",
 			args: args{t: reflect.TypeOf(ConfigObjectAttrs{})},
-			wantFields: []string{
-				"Type",
-				"Name",
-				"Active",
-				"Extensions",
-				"HAMode",
-				"OriginalAttributes",
-				"Package",
-				"PauseCalled",
-				"Paused",
-				"ResumeCalled",
-				"SourceLocation",
-				"StartCalled",
-				"StateLoaded",
-				"StopCalled",
-				"Templates",
-				"Version",
-				"Zone",
+			wantFields: map[string][]int{
+				"type":                {0, 0},
+				"name":                {1},
+				"active":              {2},
+				"extensions":          {3},
+				"ha_mode":             {4},
+				"original_attributes": {5},
+				"package":             {6},
+				"pause_called":        {7},
+				"paused":              {8},
+				"resume_called":       {9},
+				"source_location":     {10},
+				"start_called":        {11},
+				"state_loaded":        {12},
+				"stop_called":         {13},
+				"templates":           {14},
+				"version":             {15},
+				"zone":                {16},
 			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := getStructFields(tt.args.t)
-			var gotFields []string
-			for _, f := range got {
-				gotFields = append(gotFields, f.Name)
-			}
-
-			if !reflect.DeepEqual(gotFields, tt.wantFields) {
-				t.Errorf("getStructFields() = %v, wantFields %v", gotFields, tt.wantFields)
+			got := map[string][]int(planFor(tt.args.t))
+			if !reflect.DeepEqual(got, tt.wantFields) {
+				t.Errorf("planFor() = %v, wantFields %v", got, tt.wantFields)
 			}
 		})
 	}
@@ -350,8 +394,8 @@ func TestHost_UnmarshalJSON(t *testing.T) {
 		Groups         []string
 		LastHardState  int
 		LastState      int
-		LastStateDown  time.Time
-		LastStateUp    time.Time
+		LastStateDown  IcingaTime
+		LastStateUp    IcingaTime
 		State          HostState
 	}
 	type args struct {
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// limiter restricts the rate and the concurrency of the requests sent to the icinga API.
+// A single limiter is shared by all clients created from the same Icinga client.
+type limiter struct {
+	bucket *tokenBucket
+	// inFlight is a semaphore holding one element per running request
+	inFlight chan struct{}
+	onWait   func(time.Duration)
+}
+
+// newLimiter creates a limiter for the given config, or nil if no limits are configured.
+func newLimiter(config *Config) *limiter {
+	if config.QPS <= 0 && config.MaxInFlight <= 0 {
+		return nil
+	}
+
+	l := &limiter{onWait: config.OnWait}
+	if config.QPS > 0 {
+		l.bucket = newTokenBucket(config.QPS, config.Burst)
+	}
+	if config.MaxInFlight > 0 {
+		l.inFlight = make(chan struct{}, config.MaxInFlight)
+	}
+	return l
+}
+
+// wait blocks until the request may be sent and returns a function that must be called
+// once the request finished, as well as the time spent waiting.
+// A nil limiter never blocks.
+func (l *limiter) wait(ctx context.Context) (release func(), waited time.Duration, err error) {
+	release = func() {}
+	if l == nil {
+		return release, 0, nil
+	}
+
+	start := time.Now()
+	if l.bucket != nil {
+		if err := l.bucket.wait(ctx); err != nil {
+			return release, time.Since(start), err
+		}
+	}
+
+	if l.inFlight != nil {
+		select {
+		case l.inFlight <- struct{}{}:
+			release = func() { <-l.inFlight }
+		case <-ctx.Done():
+			return release, time.Since(start), ctx.Err()
+		}
+	}
+
+	waited = time.Since(start)
+	if l.onWait != nil {
+		l.onWait(waited)
+	}
+	return release, waited, nil
+}
+
+// tokenBucket is a token bucket rate limiter, refilled with qps tokens per second
+// and holding at most burst tokens.
+type tokenBucket struct {
+	mu     sync.Mutex
+	qps    float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket creates a full token bucket. A burst lower than 1 defaults to ceil(qps).
+func newTokenBucket(qps float64, burst int) *tokenBucket {
+	b := float64(burst)
+	if burst < 1 {
+		b = math.Ceil(qps)
+	}
+	return &tokenBucket{
+		qps:    qps,
+		burst:  b,
+		tokens: b,
+		last:   time.Now(),
+	}
+}
+
+// wait takes a token from the bucket, blocking until it is available or the context is done.
+func (b *tokenBucket) wait(ctx context.Context) error {
+	d := b.reserve(time.Now())
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		b.cancel()
+		return ctx.Err()
+	}
+}
+
+// reserve takes a token and returns the time the caller has to wait before using it.
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed.Seconds()*b.qps)
+		b.last = now
+	}
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.qps * float64(time.Second))
+}
+
+// cancel returns a reserved token to the bucket.
+func (b *tokenBucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = math.Min(b.burst, b.tokens+1)
+}
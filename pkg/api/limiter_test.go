@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_tokenBucket_reserve(t *testing.T) {
+	b := newTokenBucket(10, 2)
+	now := b.last
+
+	tests := []struct {
+		name  string
+		after time.Duration
+		want  time.Duration
+	}{
+		{name: "first token of burst", after: 0, want: 0},
+		{name: "second token of burst", after: 0, want: 0},
+		{name: "bucket empty", after: 0, want: 100 * time.Millisecond},
+		{name: "bucket still empty", after: 0, want: 200 * time.Millisecond},
+		{name: "bucket refilled", after: time.Second, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = now.Add(tt.after)
+			if got := b.reserve(now); got != tt.want {
+				t.Errorf("reserve() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_limiter_wait(t *testing.T) {
+	var waits int32
+	l := newLimiter(&Config{
+		MaxInFlight: 2,
+		OnWait:      func(time.Duration) { atomic.AddInt32(&waits, 1) },
+	})
+
+	var running, maxRunning int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, _, err := l.wait(context.Background())
+			if err != nil {
+				t.Errorf("wait() error = %v", err)
+				return
+			}
+			defer release()
+
+			n := atomic.AddInt32(&running, 1)
+			for {
+				m := atomic.LoadInt32(&maxRunning)
+				if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+		}()
+	}
+	wg.Wait()
+
+	if maxRunning > 2 {
+		t.Errorf("wait() allowed %d requests in flight, want at most 2", maxRunning)
+	}
+	if waits != 10 {
+		t.Errorf("OnWait called %d times, want 10", waits)
+	}
+}
+
+func Test_limiter_wait_Canceled(t *testing.T) {
+	l := newLimiter(&Config{QPS: 1, Burst: 1})
+	if _, _, err := l.wait(context.Background()); err != nil {
+		t.Fatalf("wait() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, _, err := l.wait(ctx); err == nil {
+		t.Fatalf("wait() expected error on canceled context")
+	}
+}
+
+func TestNewClientSet_SharedLimiter(t *testing.T) {
+	cs, err := NewClientSet(&Config{BaseURL: "https://icinga-server:5665/v1", QPS: 5}, nil)
+	if err != nil {
+		t.Fatalf("NewClientSet() error = %v", err)
+	}
+
+	h := cs.Hosts().(*hosts).ic
+	s := cs.Services().(*services).ic
+	a := cs.Actions().(*actions).cs
+	if h.limiter == nil || h.limiter != s.limiter || h.limiter != a.limiter {
+		t.Errorf("clients of the ClientSet do not share the same limiter")
+	}
+	if h.Client != s.Client || h.Client != a.Client {
+		t.Errorf("clients of the ClientSet do not share the same http.Client")
+	}
+}
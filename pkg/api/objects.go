@@ -2,7 +2,10 @@ package api
 
 import (
 	"encoding/json"
-	"time"
+	"fmt"
+	"sort"
+
+	"github.com/puffitos/goicinga/pkg/perfdata"
 )
 
 const Ms = 1e9
@@ -10,9 +13,9 @@ const Ms = 1e9
 // Object Hierarchy in Icinga2, mimicked by embedding structs
 // Object -> ConfigObject -> CustomVar -> Checkable -> Host/Service
 
-// Attributes represents the attributes of an icinga object.
+// Attributes represents the attributes of an icinga object, as struct or keyed by their JSON name.
 type Attributes interface {
-	CheckableAttrs | ConfigObjectAttrs | ObjectAttrs | CustomVarAttrs
+	CheckableAttrs | ConfigObjectAttrs | ObjectAttrs | CustomVarAttrs | map[string]interface{}
 }
 
 // Object represents icinga monitoring objects.
@@ -23,6 +26,43 @@ type Object interface {
 // ObjectAttrs represents the attributes of an icinga object.
 type ObjectAttrs struct {
 	Type string `json:"type"`
+
+	// loaded holds the attributes fetched from the icinga API, if only selected
+	// attributes were requested. Nil means all attributes were loaded.
+	loaded map[string]bool
+}
+
+// IsLoaded reports whether the attribute with the given JSON name was fetched from the icinga API.
+// Always true, unless the object was fetched with selected attributes only.
+func (o *ObjectAttrs) IsLoaded(attr string) bool {
+	return o.loaded == nil || o.loaded[attr]
+}
+
+// LoadedAttrs returns the JSON names of the attributes fetched from the icinga API, sorted by name,
+// or nil if all attributes were loaded.
+func (o *ObjectAttrs) LoadedAttrs() []string {
+	if o.loaded == nil {
+		return nil
+	}
+	attrs := make([]string, 0, len(o.loaded))
+	for a := range o.loaded {
+		attrs = append(attrs, a)
+	}
+	sort.Strings(attrs)
+	return attrs
+}
+
+// setLoaded marks the given attributes as the only ones fetched from the icinga API.
+// The name and type of an object are always loaded.
+func (o *ObjectAttrs) setLoaded(attrs []string) {
+	if len(attrs) == 0 {
+		o.loaded = nil
+		return
+	}
+	o.loaded = map[string]bool{"name": true, "type": true}
+	for _, a := range attrs {
+		o.loaded[a] = true
+	}
 }
 
 // ConfigObjectAttrs contains the attributes of a config object.
@@ -63,14 +103,14 @@ type CheckableAttrs struct {
 	// Not set by default (effectively 24x7).
 	CheckPeriod string `json:"check_period,omitempty"`
 	// Check command timeout in seconds. Overrides the CheckCommand’s timeout attribute
-	CheckTimeout time.Duration `json:"check_timeout,omitempty"`
+	CheckTimeout IcingaDuration `json:"check_timeout,omitempty"`
 	// The check interval (in seconds). This interval is used for
 	// checks when the object is in a HARD state. Defaults to 5m.
-	CheckInterval time.Duration `json:"check_interval,omitempty"`
+	CheckInterval IcingaDuration `json:"check_interval,omitempty"`
 	// The retry interval (in seconds). This interval is used for checks
 	// when the object is in a SOFT state. Defaults to 1m.
 	// Note: This does not affect the scheduling after a passive check result.
-	RetryInterval time.Duration `json:"retry_interval,omitempty"`
+	RetryInterval IcingaDuration `json:"retry_interval,omitempty"`
 	// Whether notifications are enabled. Defaults to true.
 	EnableNotifications bool `json:"enable_notifications,omitempty"`
 	// Whether active checks are enabled. Defaults to true.
@@ -111,9 +151,9 @@ type CheckableAttrs struct {
 	// The Acknowledgement type.
 	Acknowledgement Acknowledgement `json:"acknowledgement,omitempty"`
 	// When the acknowledgement expires (as a UNIX timestamp; 0 = no expiry).
-	AcknowledgementExpiry time.Time `json:"acknowledgement_expiry,omitempty"`
+	AcknowledgementExpiry IcingaTime `json:"acknowledgement_expiry"`
 	// When the acknowledgement has been set/cleared
-	AcknowledgementLastChange time.Time `json:"acknowledgement_last_change,omitempty"`
+	AcknowledgementLastChange IcingaTime `json:"acknowledgement_last_change"`
 	// The current check attempt number.
 	CheckAttempt int `json:"check_attempt,omitempty"`
 	// Whether the service has one or more active downtimes.
@@ -125,7 +165,7 @@ type CheckableAttrs struct {
 	// Current flapping value in percent (see flapping_thresholds)
 	FlappingCurrent float64 `json:"flapping_current,omitempty"`
 	// When the last flapping change occurred.
-	FlappingLastChange time.Time `json:"flapping_last_change,omitempty"`
+	FlappingLastChange IcingaTime `json:"flapping_last_change"`
 	// Whether next check is forced.
 	ForceNextCheck bool `json:"force_next_check,omitempty"`
 	// Whether next notification is forced.
@@ -133,25 +173,25 @@ type CheckableAttrs struct {
 	// Whether the problem is handled (downtime or acknowledgement).
 	Handled bool `json:"handled,omitempty"`
 	// When the last check occurred.
-	LastCheck time.Time `json:"last_check,omitempty"`
+	LastCheck IcingaTime `json:"last_check"`
 	// The current CheckResult.
 	LastCheckResult CheckResult `json:"last_check_result,omitempty"`
 	// When the last hard state change occurred.
-	LastHardStateChange time.Time `json:"last_hard_state_change,omitempty"`
+	LastHardStateChange IcingaTime `json:"last_hard_state_change"`
 	// Whether the service was reachable when the last check occurred.
 	LastReachable bool `json:"last_reachable,omitempty"`
 	// When the last state change occurred.
-	LastStateChange time.Time `json:"last_state_change,omitempty"`
+	LastStateChange IcingaTime `json:"last_state_change"`
 	// The previous StateType.
 	LastStateType StateType `json:"last_state_type,omitempty"`
 	// When the object was unreachable the last time.
-	LastStateUnreachable time.Time `json:"last_state_unreachable,omitempty"`
+	LastStateUnreachable IcingaTime `json:"last_state_unreachable"`
 	// When the next check occurs.
-	NextCheck time.Time `json:"next_check,omitempty"`
+	NextCheck IcingaTime `json:"next_check"`
 	// When the next check update is to be expected.
-	NextUpdate time.Time `json:"next_update,omitempty"`
+	NextUpdate IcingaTime `json:"next_update"`
 	// Previous timestamp of last_state_change before processing a new check result.
-	PreviousStateChange time.Time `json:"previous_state_change,omitempty"`
+	PreviousStateChange IcingaTime `json:"previous_state_change"`
 	// Whether the object is considered in a problem state type (NOT-OK / NOT-UP).
 	Problem bool `json:"problem,omitempty"`
 	// Calculated value of severity (https://icinga.com/docs/icinga-2/latest/doc/19-technical-concepts/#severity).
@@ -163,13 +203,13 @@ type CheckableAttrs struct {
 // CheckResult represents the results of a Service check.
 type CheckResult struct {
 	// Scheduled check execution start time.
-	ScheduleStart time.Time `json:"schedule_start,omitempty"`
+	ScheduleStart IcingaTime `json:"schedule_start"`
 	// Scheduled check execution end time.
-	ScheduleEnd time.Time `json:"schedule_end,omitempty"`
+	ScheduleEnd IcingaTime `json:"schedule_end"`
 	// Actual check execution start time.
-	ExecutionStart time.Time `json:"execution_start,omitempty"`
+	ExecutionStart IcingaTime `json:"execution_start"`
 	// Actual check execution end time.
-	ExecutionEnd time.Time `json:"execution_end,omitempty"`
+	ExecutionEnd IcingaTime `json:"execution_end"`
 	// Array of command with shell-escaped arguments or command line string.
 	Command []string `json:"command,omitempty"`
 	// The exit status returned by the check execution.
@@ -190,13 +230,31 @@ type CheckResult struct {
 	SchedulingSource string `json:"scheduling_source,omitempty"`
 	// Time-to-live duration in seconds for this check result.
 	// The next expected check result is now + ttl where freshness checks are executed.
-	TTL float64 `json:"ttl,omitempty"`
+	TTL IcingaDuration `json:"ttl,omitempty"`
 	// Internal attribute used for calculations.
 	VarsBefore map[string]interface{} `json:"vars_before,omitempty"`
 	// Internal attribute used for calculations.
 	VarsAfter map[string]interface{} `json:"vars_after,omitempty"`
 }
 
+// PerfData parses the performance data of the check result.
+func (c *CheckResult) PerfData() ([]perfdata.Value, error) {
+	var values []perfdata.Value
+	for _, s := range c.PerformanceData {
+		v, err := perfdata.Parse(s)
+		if err != nil {
+			return values, err
+		}
+		values = append(values, v...)
+	}
+	return values, nil
+}
+
+// SetPerfData replaces the performance data of the check result with the given values.
+func (c *CheckResult) SetPerfData(values ...perfdata.Value) {
+	c.PerformanceData = perfdata.Strings(values)
+}
+
 type Acknowledgement int
 
 const (
@@ -273,3 +331,59 @@ func (r *ObjectQueryResult) UnmarshalJSON(data []byte) error {
 	}
 	return json.Unmarshal(data, &aux)
 }
+
+// ObjectResult represents the result of an operation on a single object,
+// as returned by the config and actions endpoints of the icinga API.
+type ObjectResult struct {
+	// Code is the status code of the operation on the object
+	Code int `json:"code"`
+	// Name is the name of the object
+	Name string `json:"name,omitempty"`
+	// Status is the status message of the operation
+	Status string `json:"status"`
+	// Type is the type of the object
+	Type string `json:"type,omitempty"`
+	// Errors are the detailed error messages, if the operation failed
+	Errors []string `json:"errors,omitempty"`
+}
+
+// ObjectResults represents the results of an operation on one or more objects.
+type ObjectResults struct {
+	Results []ObjectResult `json:"results"`
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// Icinga returns the code as a floating point number for some endpoints.
+func (r *ObjectResult) UnmarshalJSON(data []byte) error {
+	type Alias ObjectResult
+	aux := &struct {
+		Code *float64 `json:"code"`
+		*Alias
+	}{
+		Alias: (*Alias)(r),
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if aux.Code == nil {
+		return fmt.Errorf("result without code")
+	}
+	r.Code = int(*aux.Code)
+	return nil
+}
+
+// Succeeded reports whether the operation succeeded for the object.
+func (r *ObjectResult) Succeeded() bool {
+	return r.Code >= 200 && r.Code < 300
+}
+
+// toError returns the result as error.
+func (r *ObjectResult) toError() *IcingaInternalError {
+	return &IcingaInternalError{
+		Code:   r.Code,
+		Name:   r.Name,
+		Status: r.Status,
+		Type:   r.Type,
+		Errors: r.Errors,
+	}
+}
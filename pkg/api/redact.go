@@ -0,0 +1,141 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	defaultMaxBodyLength = 4096
+	redactedValue        = "<redacted>"
+	omittedValue         = "<omitted>"
+)
+
+// DefaultSensitivePaths are the attribute paths redacted from logged bodies if none are configured.
+var DefaultSensitivePaths = []string{"*password*", "*passwd*", "*secret*", "*token*", "*api_key*"}
+
+// LogOptions control how request and response bodies show up in the logs of the client.
+type LogOptions struct {
+	// OmitBodies never logs request or response bodies.
+	OmitBodies bool
+	// MaxBodyLength truncates logged bodies to the given number of bytes.
+	// Defaults to 4096, a negative value disables truncation.
+	MaxBodyLength int
+	// SensitivePaths are the paths of the JSON attributes whose values are redacted from logged bodies.
+	// Path segments are separated by dots and may contain glob patterns, matched case-insensitively.
+	// A path matches the end of an attribute path, so "vars.*password*" redacts the passwords in the
+	// vars of every object in a body. Arrays are transparent. Defaults to DefaultSensitivePaths.
+	SensitivePaths []string
+}
+
+// redactor prepares bodies for logging, according to the LogOptions of the config.
+type redactor struct {
+	omit   bool
+	maxLen int
+	paths  [][]string
+}
+
+// newRedactor creates a redactor for the given options.
+func newRedactor(opts LogOptions) *redactor {
+	r := &redactor{omit: opts.OmitBodies, maxLen: opts.MaxBodyLength}
+	if r.maxLen == 0 {
+		r.maxLen = defaultMaxBodyLength
+	}
+	paths := opts.SensitivePaths
+	if paths == nil {
+		paths = DefaultSensitivePaths
+	}
+	for _, p := range paths {
+		r.paths = append(r.paths, strings.Split(strings.ToLower(p), "."))
+	}
+	return r
+}
+
+// defaultRedactor is used by clients which were not created with New.
+var defaultRedactor = newRedactor(LogOptions{})
+
+// body returns the loggable representation of a request or response body, with the
+// sensitive attributes redacted and truncated to the maximum length.
+// Bodies which are not valid JSON are only truncated.
+func (r *redactor) body(b []byte) string {
+	if r == nil {
+		r = defaultRedactor
+	}
+	if r.omit {
+		return omittedValue
+	}
+	if len(b) == 0 {
+		return ""
+	}
+
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err == nil {
+		var buf bytes.Buffer
+		e := json.NewEncoder(&buf)
+		e.SetEscapeHTML(false)
+		if err := e.Encode(r.redact(v, nil)); err == nil {
+			b = bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+		}
+	}
+	return r.truncate(b)
+}
+
+// redact replaces the values of all sensitive attributes in v, which is found at the given path.
+func (r *redactor) redact(v interface{}, at []string) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			p := append(at[:len(at):len(at)], strings.ToLower(k))
+			if r.sensitive(p) {
+				t[k] = redactedValue
+				continue
+			}
+			t[k] = r.redact(e, p)
+		}
+	case []interface{}:
+		for i, e := range t {
+			t[i] = r.redact(e, at)
+		}
+	}
+	return v
+}
+
+// sensitive reports whether the end of the attribute path matches any of the sensitive paths.
+func (r *redactor) sensitive(at []string) bool {
+	for _, p := range r.paths {
+		if len(p) > len(at) {
+			continue
+		}
+		tail := at[len(at)-len(p):]
+		matched := true
+		for i := range p {
+			if ok, _ := path.Match(p[i], tail[i]); !ok {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// truncate truncates the body to the maximum length. The body is cut at the start of
+// a UTF-8 character, so multi-byte characters are not split.
+func (r *redactor) truncate(b []byte) string {
+	if r.maxLen < 0 || len(b) <= r.maxLen {
+		return string(b)
+	}
+	n := r.maxLen
+	for n > 0 && !utf8.RuneStart(b[n]) {
+		n--
+	}
+	return fmt.Sprintf("%s...(truncated %d bytes)", b[:n], len(b)-n)
+}
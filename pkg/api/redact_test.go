@@ -0,0 +1,70 @@
+package api
+
+import (
+	"testing"
+)
+
+func Test_redactor_body(t *testing.T) {
+	tests := []struct {
+		name string
+		opts LogOptions
+		body string
+		want string
+	}{
+		{
+			name: "default sensitive paths",
+			opts: LogOptions{},
+			body: `{"attrs":{"address":"localhost","vars":{"db_password":"secret","API_TOKEN":"abc"}}}`,
+			want: `{"attrs":{"address":"localhost","vars":{"API_TOKEN":"<redacted>","db_password":"<redacted>"}}}`,
+		},
+		{
+			name: "arrays are transparent",
+			opts: LogOptions{SensitivePaths: []string{"vars.*pass*"}},
+			body: `{"results":[{"attrs":{"vars":{"pass":"secret","user":"root"}}},{"attrs":{"pass":"kept"}}]}`,
+			want: `{"results":[{"attrs":{"vars":{"pass":"<redacted>","user":"root"}}},{"attrs":{"pass":"kept"}}]}`,
+		},
+		{
+			name: "nested values are redacted as a whole",
+			opts: LogOptions{SensitivePaths: []string{"credentials"}},
+			body: `{"credentials":{"user":"root","password":"secret"},"ttl":1.5}`,
+			want: `{"credentials":"<redacted>","ttl":1.5}`,
+		},
+		{
+			name: "omit bodies",
+			opts: LogOptions{OmitBodies: true},
+			body: `{"attrs":{}}`,
+			want: omittedValue,
+		},
+		{
+			name: "truncated",
+			opts: LogOptions{MaxBodyLength: 10},
+			body: `{"status":"No objects found."}`,
+			want: `{"status":...(truncated 20 bytes)`,
+		},
+		{
+			name: "no truncation",
+			opts: LogOptions{MaxBodyLength: -1},
+			body: `{"status":"No objects found."}`,
+			want: `{"status":"No objects found."}`,
+		},
+		{
+			name: "not json",
+			opts: LogOptions{MaxBodyLength: 15},
+			body: `<h1>Unauthorized</h1>`,
+			want: `<h1>Unauthorize...(truncated 6 bytes)`,
+		},
+		{
+			name: "multi-byte character is not split",
+			opts: LogOptions{MaxBodyLength: 6},
+			body: `<p>Grüße aus</p>`,
+			want: `<p>Gr...(truncated 13 bytes)`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newRedactor(tt.opts).body([]byte(tt.body)); got != tt.want {
+				t.Errorf("body() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
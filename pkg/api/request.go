@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"time"
 )
 
 // Request holds the request to the icinga API
@@ -23,8 +24,16 @@ type Request struct {
 	// the object's name or action to be performed
 	object string
 
-	body io.Reader
+	// body is kept as bytes, so it can be replayed when the request is retried
+	body []byte
 	err  error
+
+	// retry overrides the retry policy of the client config
+	retry *RetryPolicy
+	// idempotent marks the request as safe to be retried, regardless of its verb
+	idempotent bool
+	// handler consumes the body of a successful response instead of buffering it, see Stream
+	handler func(body io.Reader) error
 }
 
 // NewRequest creates a new request for the given verb
@@ -79,17 +88,38 @@ func (r *Request) Body(body interface{}) *Request {
 	}
 	switch t := body.(type) {
 	case io.Reader:
-		r.body = t
+		b, err := io.ReadAll(t)
+		if err != nil {
+			r.err = err
+			return r
+		}
+		r.body = b
 	case []byte:
-		r.body = bytes.NewReader(t)
+		r.body = t
 	default:
 		b, err := json.Marshal(body)
 		if err != nil {
 			r.err = err
 			return r
 		}
-		r.body = bytes.NewReader(b)
+		r.body = b
+	}
+	return r
+}
+
+// Retry sets the retry policy for the request, overriding the one of the client config.
+// Passing nil disables retries for the request.
+func (r *Request) Retry(policy *RetryPolicy) *Request {
+	if policy == nil {
+		policy = &RetryPolicy{}
 	}
+	r.retry = policy
+	return r
+}
+
+// Idempotent marks the request as safe to be retried, even if its verb is not idempotent.
+func (r *Request) Idempotent() *Request {
+	r.idempotent = true
 	return r
 }
 
@@ -115,21 +145,103 @@ func allowedType(typ string) bool {
 // Call executes the given request and returns the result of that call.
 // Returns an error in the Result if the call failed. http.Client errors are returned directly,
 // icinga API errors are wrapped in an api.IcingaError.
+// Failed calls are retried according to the retry policy of the request or the client config.
+// While the circuit breaker of the client is open, ErrCircuitOpen is returned without calling the API.
+// The circuit breaker counts the outcome of the call once, after all retries.
 func (r *Request) Call(ctx context.Context) *Result {
-	var res Result
 	if r.err != nil {
-		res.err = r.err
-		return &res
+		return &Result{err: r.err}
 	}
+
+	done, err := r.c.breaker.allow()
+	if err != nil {
+		r.c.Log.V(1).Info("icinga api call rejected by circuit breaker",
+			"endpoint", r.endpoint, "object", r.object, "method", r.verb)
+		return &Result{err: err}
+	}
+	res := r.callWithRetry(ctx)
+	done(outcomeOf(ctx, res))
+	return res
+}
+
+// callWithRetry executes the request, retrying failed attempts according to the retry policy.
+func (r *Request) callWithRetry(ctx context.Context) *Result {
+	policy := r.retryPolicy()
+	for attempt := 1; ; attempt++ {
+		res := r.call(ctx)
+		if !r.shouldRetry(ctx, policy, attempt, res) {
+			return res
+		}
+
+		wait := policy.backoff(attempt, res.retryAfter)
+		r.c.Log.V(1).Info("retrying icinga api call",
+			"endpoint", r.endpoint, "object", r.object, "method", r.verb,
+			"attempt", attempt, "backoff", wait, "status", res.statusCode, "error", res.err)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return res
+		case <-timer.C:
+		}
+	}
+}
+
+// Stream executes the request like Call, but passes the body of a successful response to fn
+// instead of reading it into memory, e.g. to decode large results element by element.
+// Once fn was called, the request is neither retried nor sent to another endpoint.
+// Returns the error of the call or the one returned by fn.
+func (r *Request) Stream(ctx context.Context, fn func(body io.Reader) error) error {
+	r.handler = fn
+	return r.Call(ctx).Error()
+}
+
+// call executes a single attempt of the request. The request is sent to the endpoints of
+// the client in turn, until one of them is available.
+func (r *Request) call(ctx context.Context) *Result {
+	pool := r.c.endpoints
+	if pool == nil {
+		pool = newEndpointPool(r.c.Config)
+	}
+
+	endpoints := pool.order(r.verb == http.MethodGet)
+	var res *Result
+	for i, e := range endpoints {
+		res = r.callEndpoint(ctx, e.url)
+		if res.streamed || !shouldFailOver(ctx, res, r.isIdempotent()) {
+			if res.err == nil || res.statusCode != 0 {
+				pool.markHealthy(e)
+			}
+			return res
+		}
+
+		pool.markFailed(e)
+		if i < len(endpoints)-1 {
+			r.c.Log.Info("icinga endpoint unavailable, failing over",
+				"failed", e.url, "next", endpoints[i+1].url,
+				"status", res.statusCode, "error", res.err)
+		}
+	}
+	return res
+}
+
+// callEndpoint sends the request to the icinga API at the given base URL.
+func (r *Request) callEndpoint(ctx context.Context, baseURL string) *Result {
+	var res Result
 	r.c.Log.V(1).Info("calling icinga api",
-		"endpoint", r.endpoint, "object", r.object,
+		"url", baseURL, "endpoint", r.endpoint, "object", r.object,
 		"method", r.verb, "body", r.body != nil)
 
+	var body io.Reader
+	if r.body != nil {
+		body = bytes.NewReader(r.body)
+	}
 	req, err := http.NewRequestWithContext(
 		ctx,
 		r.verb,
-		strings.Join([]string{r.c.Config.BaseURL, r.endpoint, r.typ, r.object}, "/"),
-		r.body,
+		r.url(baseURL),
+		body,
 	)
 	if err != nil {
 		res.err = err
@@ -137,17 +249,37 @@ func (r *Request) Call(ctx context.Context) *Result {
 			"endpoint", r.endpoint,
 			"object", r.object,
 			"method", r.verb,
-			"body", r.body)
+			"body", r.c.redactor.body(r.body))
+		return &res
+	}
+	creds, err := r.c.Config.credentials(ctx)
+	if err != nil {
+		res.err = fmt.Errorf("failed to get credentials: %w", err)
+		r.c.Log.Error(err, "failed to get credentials", "endpoint", req.URL.Path)
 		return &res
 	}
-	req.SetBasicAuth(r.c.Config.APIUser, r.c.Config.APIPass)
+	if !creds.empty() {
+		req.SetBasicAuth(creds.Username, creds.Password)
+	}
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-HTTP-Method-Override", req.Method)
 
+	release, waited, err := r.c.limiter.wait(ctx)
+	defer release()
+	if err != nil {
+		res.err = err
+		r.c.Log.Error(err, "failed waiting for rate limiter", "endpoint", req.URL.Path, "waited", waited)
+		return &res
+	}
+	if waited > 0 {
+		r.c.Log.V(1).Info("request throttled by rate limiter", "endpoint", req.URL.Path, "waited", waited)
+	}
+
 	resp, err := r.c.Client.Do(req) //nolint:bodyclose
 	if err != nil {
 		res.err = err
+		res.transportErr = true
 		r.c.Log.Error(err, "failed to Call icinga api", "endpoint", req.URL.Path)
 		return &res
 	}
@@ -158,26 +290,79 @@ func (r *Request) Call(ctx context.Context) *Result {
 		}
 	}(resp.Body)
 
-	body, err := io.ReadAll(resp.Body)
+	res.statusCode = resp.StatusCode
+	res.retryAfter = parseRetryAfter(resp.Header)
+	if r.handler != nil && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		r.c.Log.V(1).Info("streaming response from icinga api", "status", resp.StatusCode)
+		res.streamed = true
+		res.err = r.handler(resp.Body)
+		return &res
+	}
+	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		res.err = err
 		r.c.Log.Error(err, "failed reading response body")
 		return &res
 	}
-	r.c.Log.V(1).Info("response from icinga api", "status", resp.StatusCode, "body", string(body))
+	if log := r.c.Log.V(1); log.Enabled() {
+		log.Info("response from icinga api", "status", resp.StatusCode, "body", r.c.redactor.body(respBody))
+	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
-		res.err = WrapError(body)
+		res.err = wrapStatusError(resp.StatusCode, respBody)
 	}
-	res.statusCode = resp.StatusCode
-	res.body = body
+	res.body = respBody
 	return &res
 }
 
+// url returns the URL of the request for the given base URL, skipping empty path segments.
+func (r *Request) url(baseURL string) string {
+	parts := []string{baseURL}
+	for _, p := range []string{r.endpoint, r.typ, r.object} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// isIdempotent reports whether the request may be safely repeated.
+func (r *Request) isIdempotent() bool {
+	return r.idempotent || isIdempotentVerb(r.verb)
+}
+
+// retryPolicy returns the retry policy of the request, falling back to the one of the client config.
+func (r *Request) retryPolicy() *RetryPolicy {
+	if r.retry != nil {
+		return r.retry
+	}
+	return r.c.Config.Retry
+}
+
+// shouldRetry reports whether the request should be attempted again after the given result.
+func (r *Request) shouldRetry(ctx context.Context, policy *RetryPolicy, attempt int, res *Result) bool {
+	if policy == nil || attempt >= policy.MaxAttempts || res.err == nil || res.streamed || ctx.Err() != nil {
+		return false
+	}
+	if !r.isIdempotent() && !policy.RetryNonIdempotent {
+		return false
+	}
+	if res.statusCode != 0 {
+		return policy.retryableStatus(res.statusCode)
+	}
+	return isRetryableError(ctx, res.err)
+}
+
 // Result holds the response from the icinga API
 type Result struct {
 	statusCode int
 	body       []byte
 	err        error
+	// retryAfter is the wait time requested by the server via the Retry-After header
+	retryAfter time.Duration
+	// transportErr is set if err was returned by the http.Client, i.e. no response was received
+	transportErr bool
+	// streamed is set if the body was passed to the handler of the request
+	streamed bool
 }
 
 // Into decodes the response body into the given interface
@@ -193,3 +378,36 @@ func (r *Result) Into(v interface{}) error {
 func (r *Result) Error() error {
 	return r.err
 }
+
+// Results decodes the per-object results of the response, which are returned by the
+// config and actions endpoints. The results are available even if the call failed,
+// as long as the icinga API responded with them.
+func (r *Result) Results() ([]ObjectResult, error) {
+	if len(r.body) == 0 {
+		if r.err != nil {
+			return nil, r.err
+		}
+		return nil, nil
+	}
+	var results ObjectResults
+	if err := json.Unmarshal(r.body, &results); err != nil {
+		return nil, err
+	}
+	return results.Results, nil
+}
+
+// Succeeded returns the results of the objects the operation succeeded for,
+// e.g. to tell them apart from the failed ones in ResultErrors.
+func (r *Result) Succeeded() []ObjectResult {
+	results, err := r.Results()
+	if err != nil {
+		return nil
+	}
+	succeeded := make([]ObjectResult, 0, len(results))
+	for i := range results {
+		if results[i].Succeeded() {
+			succeeded = append(succeeded, results[i])
+		}
+	}
+	return succeeded
+}
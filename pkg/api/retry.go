@@ -0,0 +1,148 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultInitialBackoff = 100 * time.Millisecond
+	defaultMaxBackoff     = 10 * time.Second
+	defaultMultiplier     = 2.0
+)
+
+// RetryPolicy configures how failed requests to the icinga API are retried.
+// By default, only requests with idempotent verbs (GET, HEAD, PUT, DELETE) or requests
+// explicitly marked as idempotent are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	// Values lower than 2 disable retries.
+	MaxAttempts int
+	// InitialBackoff is the time to wait before the first retry. Defaults to 100ms.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the time to wait between two attempts. Defaults to 10s.
+	MaxBackoff time.Duration
+	// Multiplier is the factor the backoff grows with after each attempt. Defaults to 2.
+	Multiplier float64
+	// Jitter is the fraction (between 0 and 1) of the backoff which is randomized.
+	// A jitter of 0 disables randomization.
+	Jitter float64
+	// RetryableStatusCodes are the HTTP status codes which trigger a retry.
+	// Defaults to 429, 502, 503 and 504.
+	RetryableStatusCodes []int
+	// RetryNonIdempotent allows retrying requests with non-idempotent verbs (POST).
+	RetryNonIdempotent bool
+}
+
+// DefaultRetryPolicy returns a retry policy with 3 attempts, exponential backoff and 20% jitter.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: defaultInitialBackoff,
+		MaxBackoff:     defaultMaxBackoff,
+		Multiplier:     defaultMultiplier,
+		Jitter:         0.2, //nolint:gomnd
+	}
+}
+
+// backoff returns the time to wait after the given (1-based) attempt failed.
+// A retryAfter hint sent by the server takes precedence, but is still capped by MaxBackoff.
+func (p *RetryPolicy) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	initial, maxBackoff, mult := p.InitialBackoff, p.MaxBackoff, p.Multiplier
+	if initial <= 0 {
+		initial = defaultInitialBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxBackoff
+	}
+	if mult < 1 {
+		mult = defaultMultiplier
+	}
+
+	if retryAfter > 0 {
+		return minDuration(retryAfter, maxBackoff)
+	}
+
+	d := float64(initial) * math.Pow(mult, float64(attempt-1))
+	if d > float64(maxBackoff) {
+		d = float64(maxBackoff)
+	}
+	if p.Jitter > 0 {
+		j := math.Min(p.Jitter, 1)
+		d = d*(1-j) + d*j*rand.Float64() //nolint:gosec
+	}
+	return time.Duration(d)
+}
+
+// retryableStatus reports whether the given status code should be retried.
+func (p *RetryPolicy) retryableStatus(code int) bool {
+	codes := p.RetryableStatusCodes
+	if codes == nil {
+		codes = []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		}
+	}
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// isIdempotentVerb reports whether the given HTTP verb may be safely repeated.
+func isIdempotentVerb(verb string) bool {
+	switch verb {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// isRetryableError reports whether the error returned by the http.Client is
+// a transient network error worth retrying. Timeouts of the http.Client are retried,
+// errors caused by the end of the context of the caller are not.
+func isRetryableError(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNABORTED) || errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// parseRetryAfter parses the Retry-After header, if it contains a number of seconds.
+func parseRetryAfter(h http.Header) time.Duration {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	s, err := strconv.Atoi(v)
+	if err != nil || s < 0 {
+		return 0
+	}
+	return time.Duration(s) * time.Second
+}
+
+func minDuration(a, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,258 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/jarcoal/httpmock"
+)
+
+func TestRequest_Call_Retry(t *testing.T) {
+	tests := []struct {
+		name         string
+		verb         string
+		idempotent   bool
+		policy       *RetryPolicy
+		responses    []int
+		netErr       error
+		wantAttempts int
+		wantErr      bool
+	}{
+		{
+			name:         "no policy",
+			verb:         http.MethodGet,
+			policy:       nil,
+			responses:    []int{http.StatusServiceUnavailable, http.StatusOK},
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "retry until success",
+			verb:         http.MethodGet,
+			policy:       testRetryPolicy(3),
+			responses:    []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK},
+			wantAttempts: 3,
+			wantErr:      false,
+		},
+		{
+			name:         "max attempts exhausted",
+			verb:         http.MethodDelete,
+			policy:       testRetryPolicy(2),
+			responses:    []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK},
+			wantAttempts: 2,
+			wantErr:      true,
+		},
+		{
+			name:         "non retryable status",
+			verb:         http.MethodGet,
+			policy:       testRetryPolicy(3),
+			responses:    []int{http.StatusNotFound, http.StatusOK},
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "non idempotent verb",
+			verb:         http.MethodPost,
+			policy:       testRetryPolicy(3),
+			responses:    []int{http.StatusServiceUnavailable, http.StatusOK},
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "non idempotent verb marked as idempotent",
+			verb:         http.MethodPost,
+			idempotent:   true,
+			policy:       testRetryPolicy(3),
+			responses:    []int{http.StatusServiceUnavailable, http.StatusOK},
+			wantAttempts: 2,
+			wantErr:      false,
+		},
+		{
+			name:         "connection reset",
+			verb:         http.MethodGet,
+			policy:       testRetryPolicy(3),
+			netErr:       syscall.ECONNRESET,
+			responses:    []int{0, http.StatusOK},
+			wantAttempts: 2,
+			wantErr:      false,
+		},
+		{
+			name:         "non retryable network error",
+			verb:         http.MethodGet,
+			policy:       testRetryPolicy(3),
+			netErr:       fmt.Errorf("error"),
+			responses:    []int{0, http.StatusOK},
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+	}
+
+	c := newTestClient()
+	httpmock.ActivateNonDefault(c.Client)
+	defer httpmock.DeactivateAndReset()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int
+			var bodies []string
+			url := fmt.Sprintf("%s/objects/hosts/test", c.Config.BaseURL)
+			httpmock.RegisterResponder(tt.verb, url, func(req *http.Request) (*http.Response, error) {
+				b, _ := io.ReadAll(req.Body)
+				bodies = append(bodies, string(b))
+				code := tt.responses[attempts]
+				attempts++
+				if code == 0 {
+					return nil, tt.netErr
+				}
+				return httpmock.NewStringResponse(code, `{"error":503,"status":"Icinga is reloading."}`), nil
+			})
+
+			r := c.Verb(tt.verb).
+				Endpoint("objects").
+				Type("hosts").
+				Object("test").
+				Body(map[string]string{"key": "value"}).
+				Retry(tt.policy)
+			if tt.idempotent {
+				r.Idempotent()
+			}
+
+			err := r.Call(context.Background()).Error()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Call() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts {
+				t.Fatalf("Call() attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+			for i, b := range bodies {
+				if b != `{"key":"value"}` {
+					t.Errorf("Call() attempt %d sent body %q", i+1, b)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     *RetryPolicy
+		attempt    int
+		retryAfter time.Duration
+		min        time.Duration
+		max        time.Duration
+	}{
+		{
+			name:    "defaults",
+			policy:  &RetryPolicy{},
+			attempt: 1,
+			min:     defaultInitialBackoff,
+			max:     defaultInitialBackoff,
+		},
+		{
+			name:    "exponential",
+			policy:  &RetryPolicy{InitialBackoff: time.Second, Multiplier: 2},
+			attempt: 3,
+			min:     4 * time.Second,
+			max:     4 * time.Second,
+		},
+		{
+			name:    "capped",
+			policy:  &RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 3 * time.Second},
+			attempt: 5,
+			min:     3 * time.Second,
+			max:     3 * time.Second,
+		},
+		{
+			name:    "jitter",
+			policy:  &RetryPolicy{InitialBackoff: time.Second, Jitter: 0.5},
+			attempt: 1,
+			min:     500 * time.Millisecond,
+			max:     time.Second,
+		},
+		{
+			name:       "retry after",
+			policy:     &RetryPolicy{InitialBackoff: time.Second},
+			attempt:    1,
+			retryAfter: 5 * time.Second,
+			min:        5 * time.Second,
+			max:        5 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.policy.backoff(tt.attempt, tt.retryAfter)
+			if got < tt.min || got > tt.max {
+				t.Errorf("backoff() = %v, want between %v and %v", got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+// testRetryPolicy returns a retry policy with the given attempts and a negligible backoff.
+func testRetryPolicy(attempts int) *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    attempts,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+	}
+}
+
+// newHangingServer starts a server which hangs on the first n requests, until the client gives up,
+// and answers the others with 200. The number of requests is counted in calls.
+func newHangingServer(t *testing.T, n int32, calls *int32) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(calls, 1) <= n {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		_, _ = io.WriteString(w, `{"results":[]}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRequest_Call_RetryTimeout(t *testing.T) {
+	var calls int32
+	srv := newHangingServer(t, 1, &calls)
+	l := logr.Discard()
+	c, err := New(&Config{BaseURL: srv.URL + "/v1", Timeout: 50 * time.Millisecond, Retry: testRetryPolicy(3)}, &l)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if err := c.Get().Endpoint("objects").Type("hosts").Call(context.Background()).Error(); err != nil {
+		t.Fatalf("Call() error = %v, want the timed out request to be retried", err)
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("Call() sent %d requests, want 2", got)
+	}
+
+	// a request canceled by the caller is not retried
+	calls = 0
+	srv = newHangingServer(t, 3, &calls)
+	c, err = New(&Config{BaseURL: srv.URL + "/v1", Timeout: time.Second, Retry: testRetryPolicy(3)}, &l)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := c.Get().Endpoint("objects").Type("hosts").Call(ctx).Error(); err == nil {
+		t.Fatalf("Call() error = nil, want the error of the canceled request")
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("Call() sent %d requests after the caller canceled, want 1", got)
+	}
+}
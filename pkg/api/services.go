@@ -2,13 +2,9 @@ package api
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"reflect"
-	"strings"
-	"time"
 
-	"github.com/go-logr/logr"
+	"github.com/puffitos/goicinga/pkg/perfdata"
 )
 
 type ServiceState int
@@ -20,6 +16,29 @@ const (
 	ServiceUnknown
 )
 
+// ServiceStateFor returns the state of a service measuring the given value: critical if it
+// alerts on the crit range, warning if it alerts on the warn range and ok otherwise.
+// Nil ranges never alert.
+func ServiceStateFor(value float64, warn, crit *perfdata.Range) ServiceState {
+	switch {
+	case crit != nil && crit.Alerts(value):
+		return ServiceCritical
+	case warn != nil && warn.Alerts(value):
+		return ServiceWarning
+	default:
+		return ServiceOk
+	}
+}
+
+// PerfDataState returns the state of a service measuring the performance data value,
+// evaluated against its own thresholds. Unknown values are ServiceUnknown.
+func PerfDataState(v perfdata.Value) ServiceState {
+	if v.Unknown {
+		return ServiceUnknown
+	}
+	return ServiceStateFor(v.Value, v.Warn, v.Crit)
+}
+
 type Service struct {
 	CheckableAttrs
 	DisplayName       string       `json:"display_name"`
@@ -27,67 +46,26 @@ type Service struct {
 	HostName          string       `json:"host_name"`
 	LastHardState     int          `json:"last_hard_state"`
 	LastState         int          `json:"last_state"`
-	LastStateCritical time.Time    `json:"last_state_critical"`
-	LastStateOK       time.Time    `json:"last_state_ok"`
-	LastStateUnknown  time.Time    `json:"last_state_unknown"`
-	LastStateWarning  time.Time    `json:"last_state_warning"`
+	LastStateCritical IcingaTime   `json:"last_state_critical"`
+	LastStateOK       IcingaTime   `json:"last_state_ok"`
+	LastStateUnknown  IcingaTime   `json:"last_state_unknown"`
+	LastStateWarning  IcingaTime   `json:"last_state_warning"`
 	State             ServiceState `json:"state"`
 }
 
 // UnmarshalJSON implements the json.Unmarshaler interface.
 // The data is expected to be the binary representation of a ObjectQueryResult.
 func (s *Service) UnmarshalJSON(data []byte) error {
-	var oqr ObjectQueryResult
-	if err := json.Unmarshal(data, &oqr); err != nil {
-		return err
-	}
-
-	// iterate over all fields of the Service struct and set them to their
-	// corresponding fields found in the oqr.Attrs map.
-	elem := reflect.ValueOf(s).Elem()
-	fields := getStructFields(elem.Type())
-	var found int
-	for _, v := range fields {
-		n := strings.Split(v.Tag.Get("json"), ",")[0]
-		if oqrv, ok := oqr.Attrs[n]; ok {
-			found++
-			typ := v.Type
-			// The State field is of type ServiceState, but the API returns an int.
-			// This makes sure that the CheckResult.State field is of type ServiceState.
-			switch v.Name {
-			case "State":
-				typ = reflect.TypeOf(ServiceState(0))
-			case "Groups":
-				slice := reflect.MakeSlice(typ, len(oqrv.([]interface{})), len(oqrv.([]interface{})))
-				for _, e := range oqrv.([]interface{}) {
-					slice = reflect.Append(slice, reflect.ValueOf(e))
-				}
-				elem.FieldByName(v.Name).Set(slice)
-				continue
-			}
-			if typ == reflect.TypeOf(time.Time{}) {
-				seconds := int64(oqrv.(float64))
-				nanoseconds := int64((oqrv.(float64) - float64(seconds)) * Ms)
-				t := time.Unix(seconds, nanoseconds).UTC()
-				elem.FieldByName(v.Name).Set(reflect.ValueOf(t))
-				continue
-			}
-			value := reflect.ValueOf(oqrv).Convert(typ)
-			elem.FieldByName(v.Name).Set(value)
-		}
-	}
-
-	if found == 0 {
-		return fmt.Errorf("no known fields found in Attrs map of ObjectQueryResult")
-	}
-
-	type Alias Service
-	return json.Unmarshal(data, (*Alias)(s))
+	return decodeObject(data, s)
 }
 
 type Services interface {
-	Get(ctx context.Context, name string) (*Service, error)
+	Get(ctx context.Context, name string, attrs ...string) (*Service, error)
+	List(ctx context.Context, query *ObjectQuery) ([]*Service, error)
+	Stream(ctx context.Context, query *ObjectQuery, fn func(svc *Service) error) error
 	Create(ctx context.Context, svc *Service) error
+	UpdateAttrs(ctx context.Context, name string, attrs map[string]interface{}) error
+	Patch(ctx context.Context, old, new *Service) error
 	Delete(ctx context.Context, name string, cascade bool) error
 }
 
@@ -97,35 +75,82 @@ type services struct {
 }
 
 // newServicesClient returns a new Services client.
-func newServicesClient(cfg *Config, log *logr.Logger) *services {
-	l := log.WithName("services")
-	return &services{ic: New(cfg, &l)}
+func newServicesClient(ic *Icinga) *services {
+	return &services{ic: ic.named("services")}
 }
 
-// Get returns the service with the given name on the given host.
-func (c *services) Get(ctx context.Context, name string) (*Service, error) {
+// Get returns the service with the given name on the given host, or nil and an error
+// satisfying IsNotFound if it doesn't exist.
+// If attrs are given, only those attributes are fetched and the others are left empty,
+// see ObjectAttrs.IsLoaded.
+func (c *services) Get(ctx context.Context, name string, attrs ...string) (*Service, error) {
 	if name == "" {
 		return nil, &NoIdentifierError{Object: "service"}
 	}
-	var res Service
-	err := c.ic.Get().
+	r := c.ic.Get().
 		Endpoint("objects").
 		Type("services").
-		Object(name).
-		Call(ctx).
-		Into(&res)
-	return &res, err
+		Object(name)
+	if len(attrs) > 0 {
+		r = r.Body(&ObjectQuery{Attrs: attrs})
+	}
+	res, err := getObject[Service](ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	res.setLoaded(attrs)
+	return res, nil
+}
+
+// List returns all services matching the query, or all services if the query is nil.
+// The results are decoded while they are received, use Stream to process them one by one
+// without holding all of them in memory.
+func (c *services) List(ctx context.Context, query *ObjectQuery) ([]*Service, error) {
+	var res []*Service
+	err := c.Stream(ctx, query, func(svc *Service) error {
+		res = append(res, svc)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Stream decodes the services matching the query one by one and passes them to fn,
+// or all services if the query is nil. Memory use is bounded by the size of a single service.
+// Streaming stops at the first error returned by fn, which is returned.
+// If the query selects attributes, only those are loaded, see ObjectAttrs.IsLoaded.
+func (c *services) Stream(ctx context.Context, query *ObjectQuery, fn func(svc *Service) error) error {
+	if fn == nil {
+		return fmt.Errorf("callback cannot be nil")
+	}
+	r := c.ic.Get().
+		Endpoint("objects").
+		Type("services")
+	var attrs []string
+	if query != nil {
+		r = r.Body(query)
+		attrs = query.Attrs
+	}
+	return streamObjects(ctx, r, func(svc *Service) error {
+		svc.setLoaded(attrs)
+		return fn(svc)
+	})
 }
 
-// Create creates a new types in Icinga with the given name, if it doesn't already exist.
+// Create creates the given service with its templates and the attributes which are set, including
+// those which cannot be modified afterwards, like the zone and groups.
+// The name is either the full name "<host>!<service>", or the short name with the HostName set.
 func (c *services) Create(ctx context.Context, svc *Service) error {
 	if svc == nil {
 		return fmt.Errorf("service cannot be nil")
 	}
 
-	b := &CreateObjectRequest[CheckableAttrs]{
+	// only the set attributes are sent, so the defaults of icinga and the templates apply
+	b := &CreateObjectRequest[map[string]interface{}]{
 		Templates: svc.Templates,
-		Attrs:     svc.CheckableAttrs,
+		Attrs:     objectAttrs(svc, creationAttrs, true),
 	}
 	res := c.ic.Put().
 		Endpoint("objects").
@@ -137,6 +162,35 @@ func (c *services) Create(ctx context.Context, svc *Service) error {
 	return res.Error()
 }
 
+// UpdateAttrs modifies the given attributes of the service with the given name.
+// The attributes are keyed by their JSON name, single custom variables can be set as "vars.<name>".
+func (c *services) UpdateAttrs(ctx context.Context, name string, attrs map[string]interface{}) error {
+	if name == "" {
+		return &NoIdentifierError{Object: "service"}
+	}
+	if err := validateAttrs(attrs); err != nil {
+		return err
+	}
+	return updateAttrs(ctx, c.ic, "services", name, attrs)
+}
+
+// Patch modifies the writable attributes which differ between the old and the new service.
+// Changes of attributes which can only be set on creation, like the zone and groups, are not sent.
+// No request is sent if nothing changed.
+func (c *services) Patch(ctx context.Context, old, new *Service) error {
+	if old == nil || new == nil {
+		return fmt.Errorf("service cannot be nil")
+	}
+	if new.Name == "" {
+		return &NoIdentifierError{Object: "service"}
+	}
+	attrs := diffAttrs(old, new)
+	if len(attrs) == 0 {
+		return nil
+	}
+	return updateAttrs(ctx, c.ic, "services", new.Name, attrs)
+}
+
 // Delete deletes the given service from Icinga.
 func (c *services) Delete(ctx context.Context, name string, cascade bool) error {
 	if name == "" {
@@ -2,15 +2,21 @@ package api
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/go-logr/logr"
 	"github.com/go-logr/zapr"
 	"github.com/jarcoal/httpmock"
 	"github.com/kr/pretty"
+	"github.com/puffitos/goicinga/pkg/perfdata"
 	"go.uber.org/zap"
 )
 
@@ -44,9 +50,17 @@ func Test_services_Get(t *testing.T) {
 			svcName:  "test",
 			want:     testService(),
 			wantCode: http.StatusOK,
-			wantBody: testServiceQueryResult(),
+			wantBody: `{"results":[` + testServiceQueryResult() + `]}`,
 			wantErr:  false,
 		},
+		{
+			name:     "no results",
+			svcName:  "test",
+			want:     nil,
+			wantCode: http.StatusOK,
+			wantBody: `{"results":[]}`,
+			wantErr:  true,
+		},
 	}
 
 	c := services{
@@ -66,6 +80,9 @@ func Test_services_Get(t *testing.T) {
 			if (err != nil) != tt.wantErr {
 				t.Fatalf("Get() error = %v, wantErr %v", err, tt.wantErr)
 			}
+			if tt.wantCode != 0 && tt.want == nil && !IsNotFound(err) {
+				t.Errorf("Get() error = %v, want a not found error", err)
+			}
 
 			if err == nil && !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("Get() got = %v, want %v", got, tt.want)
@@ -138,6 +155,79 @@ func Test_services_Create(t *testing.T) {
 	}
 }
 
+func Test_services_Create_body(t *testing.T) {
+	tests := []struct {
+		name string
+		svc  func() *Service
+		path string
+		want string
+	}{
+		{
+			name: "template only",
+			svc: func() *Service {
+				svc := &Service{}
+				svc.Name = "web1!load"
+				svc.Templates = []string{"passive-service"}
+				return svc
+			},
+			path: "/v1/objects/services/web1!load",
+			want: `{"templates":["passive-service"],"attrs":{}}`,
+		},
+		{
+			name: "template with host name",
+			svc: func() *Service {
+				svc := &Service{HostName: "web1"}
+				svc.Name = "web1!load"
+				svc.Templates = []string{"passive-service"}
+				return svc
+			},
+			path: "/v1/objects/services/web1!load",
+			want: `{"templates":["passive-service"],"attrs":{"host_name":"web1"}}`,
+		},
+		{
+			name: "set attributes",
+			svc: func() *Service {
+				svc := &Service{}
+				svc.Name = "web1!load"
+				svc.CheckCommand = "load"
+				svc.CheckInterval = IcingaDuration(time.Minute)
+				svc.EnableActiveChecks = true
+				return svc
+			},
+			path: "/v1/objects/services/web1!load",
+			want: `{"attrs":{"check_command":"load","check_interval":60,"enable_active_checks":true}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path, body string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := io.ReadAll(r.Body)
+				path, body = r.URL.Path, string(b)
+				_, _ = io.WriteString(w, `{"results":[{"code":200.0,"status":"Object was created"}]}`)
+			}))
+			defer srv.Close()
+
+			l := logr.Discard()
+			ic, err := New(&Config{BaseURL: srv.URL + "/v1", Timeout: time.Second}, &l)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			c := &services{ic: ic}
+			if err := c.Create(context.Background(), tt.svc()); err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			if path != tt.path {
+				t.Errorf("Create() sent PUT %s, want %s", path, tt.path)
+			}
+			if strings.TrimSpace(body) != tt.want {
+				t.Errorf("Create() sent body %s, want %s", body, tt.want)
+			}
+		})
+	}
+}
+
 func Test_services_Delete(t *testing.T) {
 	type args struct {
 		name    string
@@ -259,12 +349,12 @@ func TestService_UnmarshalJSON(t *testing.T) {
 // for a successful service query.
 func testServiceQueryResult() string {
 	s := testService()
-	// convert time.Time to unix timestamp with nanoseconds
-	lastOK := float64(s.LastStateOK.UnixNano() / int64(time.Second))
-	lastWarning := float64(s.LastStateWarning.UnixNano() / int64(time.Second))
-	lastCritical := float64(s.LastStateCritical.UnixNano() / int64(time.Second))
-	lastUnknown := float64(s.LastStateUnknown.UnixNano() / int64(time.Second))
-	lastCheck := float64(s.LastCheck.UnixNano() / int64(time.Second))
+	// convert the times to unix timestamps in seconds
+	lastOK := s.LastStateOK.Seconds()
+	lastWarning := s.LastStateWarning.Seconds()
+	lastCritical := s.LastStateCritical.Seconds()
+	lastUnknown := s.LastStateUnknown.Seconds()
+	lastCheck := s.LastCheck.Seconds()
 
 	qr := ObjectQueryResult{
 		Name: s.Name,
@@ -296,11 +386,11 @@ func testServiceQueryResult() string {
 
 // testService returns a new Service for testing.
 func testService() *Service {
-	lastCheck := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
-	lastOK := time.Date(2020, 3, 1, 0, 0, 3, 0, time.UTC)
-	lastCritical := time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC)
-	lastUnknown := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
-	lastWarning := time.Date(2020, 1, 5, 0, 0, 0, 0, time.UTC)
+	lastCheck := NewIcingaTime(time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC))
+	lastOK := NewIcingaTime(time.Date(2020, 3, 1, 0, 0, 3, 0, time.UTC))
+	lastCritical := NewIcingaTime(time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC))
+	lastUnknown := NewIcingaTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	lastWarning := NewIcingaTime(time.Date(2020, 1, 5, 0, 0, 0, 0, time.UTC))
 	return &Service{
 		CheckableAttrs: CheckableAttrs{
 			CustomVarAttrs: CustomVarAttrs{
@@ -337,5 +427,120 @@ func newTestClient() *Icinga {
 	}
 
 	l := zapr.NewLogger(zap.NewExample().Named("test"))
-	return New(cfg, &l)
+	ic, err := New(cfg, &l)
+	if err != nil {
+		panic(err)
+	}
+	return ic
+}
+
+func Test_services_projection(t *testing.T) {
+	c := services{ic: newTestClient()}
+	httpmock.ActivateNonDefault(c.ic.Client)
+	defer httpmock.DeactivateAndReset()
+
+	var gotQuery ObjectQuery
+	body := `{"name":"host!svc","type":"Service","attrs":{"host_name":"host","state":2.0}}`
+	responder := func(req *http.Request) (*http.Response, error) {
+		gotQuery = ObjectQuery{}
+		if req.Body != nil && req.ContentLength != 0 {
+			if err := json.NewDecoder(req.Body).Decode(&gotQuery); err != nil {
+				return nil, err
+			}
+		}
+		return httpmock.NewStringResponse(http.StatusOK, `{"results":[`+body+`]}`), nil
+	}
+	httpmock.RegisterResponder(http.MethodGet, c.ic.Config.BaseURL+"/objects/services/host!svc", responder)
+	httpmock.RegisterResponder(http.MethodGet, c.ic.Config.BaseURL+"/objects/services", responder)
+
+	svc, err := c.Get(context.Background(), "host!svc", "host_name", "state")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if !reflect.DeepEqual(gotQuery.Attrs, []string{"host_name", "state"}) {
+		t.Errorf("Get() requested attrs %v", gotQuery.Attrs)
+	}
+	if svc.State != ServiceCritical || svc.HostName != "host" {
+		t.Errorf("Get() = %v, want the critical service", svc)
+	}
+	for attr, want := range map[string]bool{"name": true, "type": true, "state": true, "host_name": true, "last_check_result": false} {
+		if got := svc.IsLoaded(attr); got != want {
+			t.Errorf("IsLoaded(%q) = %v, want %v", attr, got, want)
+		}
+	}
+	if got, want := svc.LoadedAttrs(), []string{"host_name", "name", "state", "type"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadedAttrs() = %v, want %v", got, want)
+	}
+
+	list, err := c.List(context.Background(), &ObjectQuery{Attrs: []string{"state"}})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(list) != 1 || !list[0].IsLoaded("state") || list[0].IsLoaded("host_name") {
+		t.Errorf("List() = %v, want the service with only state loaded", list)
+	}
+
+	// without projection all attributes are loaded
+	svc, err = c.Get(context.Background(), "host!svc")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if gotQuery.Attrs != nil || !svc.IsLoaded("last_check_result") || svc.LoadedAttrs() != nil {
+		t.Errorf("Get() without attrs sent %v, loaded %v", gotQuery.Attrs, svc.LoadedAttrs())
+	}
+}
+
+func TestCheckResult_PerfData(t *testing.T) {
+	var cr CheckResult
+	cr.SetPerfData(perfdata.New("load1", 0.5, "").WithWarn(perfdata.NewRange(0, 1)), perfdata.New("free space", 10, "GB"))
+	if want := []string{"load1=0.5;1", "'free space'=10GB"}; !reflect.DeepEqual(cr.PerformanceData, want) {
+		t.Errorf("SetPerfData() = %v, want %v", cr.PerformanceData, want)
+	}
+
+	cr.PerformanceData = append(cr.PerformanceData, "time=1s rx=2c")
+	values, err := cr.PerfData()
+	if err != nil {
+		t.Fatalf("PerfData() error = %v", err)
+	}
+	if len(values) != 4 || values[1].Label != "free space" || values[3].UOM != "c" {
+		t.Errorf("PerfData() = %+v", values)
+	}
+
+	cr.PerformanceData = []string{"broken"}
+	if _, err := cr.PerfData(); err == nil {
+		t.Errorf("PerfData() error = nil, want an error for invalid perfdata")
+	}
+}
+
+func TestServiceStateFor(t *testing.T) {
+	warn, _ := perfdata.ParseRange("80")
+	crit, _ := perfdata.ParseRange("90")
+	tests := []struct {
+		name       string
+		value      float64
+		warn, crit *perfdata.Range
+		want       ServiceState
+	}{
+		{name: "ok", value: 50, warn: &warn, crit: &crit, want: ServiceOk},
+		{name: "warning", value: 85, warn: &warn, crit: &crit, want: ServiceWarning},
+		{name: "critical", value: 95, warn: &warn, crit: &crit, want: ServiceCritical},
+		{name: "critical without warn", value: 95, crit: &crit, want: ServiceCritical},
+		{name: "no thresholds", value: 95, want: ServiceOk},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ServiceStateFor(tt.value, tt.warn, tt.crit); got != tt.want {
+				t.Errorf("ServiceStateFor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	v, _ := perfdata.ParseValue("disk=95%;80;90")
+	if got := PerfDataState(v); got != ServiceCritical {
+		t.Errorf("PerfDataState() = %v, want %v", got, ServiceCritical)
+	}
+	v, _ = perfdata.ParseValue("disk=U;80;90")
+	if got := PerfDataState(v); got != ServiceUnknown {
+		t.Errorf("PerfDataState() = %v, want %v", got, ServiceUnknown)
+	}
 }
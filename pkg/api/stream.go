@@ -0,0 +1,94 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// decodeResults decodes the elements of the results array of a response one by one,
+// and passes each of them to fn. Only a single element is held in memory at a time.
+// Other members of the response are skipped.
+func decodeResults(body io.Reader, fn func(raw json.RawMessage) error) error {
+	d := json.NewDecoder(body)
+	if err := expectDelim(d, '{'); err != nil {
+		return err
+	}
+	for d.More() {
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
+		if key, ok := t.(string); !ok || key != "results" {
+			var skip json.RawMessage
+			if err := d.Decode(&skip); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := expectDelim(d, '['); err != nil {
+			return err
+		}
+		for d.More() {
+			var raw json.RawMessage
+			if err := d.Decode(&raw); err != nil {
+				return err
+			}
+			if err := fn(raw); err != nil {
+				return err
+			}
+		}
+		if err := expectDelim(d, ']'); err != nil {
+			return err
+		}
+	}
+	return expectDelim(d, '}')
+}
+
+// expectDelim reads the next token and returns an error if it is not the given delimiter.
+func expectDelim(d *json.Decoder, delim json.Delim) error {
+	t, err := d.Token()
+	if err != nil {
+		return err
+	}
+	if t != delim {
+		return fmt.Errorf("invalid response: expected %s, got %v", delim, t)
+	}
+	return nil
+}
+
+// streamObjects executes the request and decodes the results into objects of type T one by one,
+// passing each of them to fn. Decoding stops at the first error returned by fn.
+func streamObjects[T Object](ctx context.Context, r *Request, fn func(obj *T) error) error {
+	return r.Stream(ctx, func(body io.Reader) error {
+		return decodeResults(body, func(raw json.RawMessage) error {
+			var obj T
+			if err := json.Unmarshal(raw, &obj); err != nil {
+				return err
+			}
+			return fn(&obj)
+		})
+	})
+}
+
+// getObject executes the request for a single object and decodes the first element of the
+// results, like streamObjects. Returns a not found error if the results are empty.
+func getObject[T Object](ctx context.Context, r *Request) (*T, error) {
+	var res *T
+	err := streamObjects(ctx, r, func(obj *T) error {
+		if res == nil {
+			res = obj
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return nil, &IcingaError{Err: http.StatusNotFound, Status: "No objects found."}
+	}
+	return res, nil
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+)
+
+func Test_decodeResults(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "results",
+			body: `{"results":[{"name":"a"},{"name":"b"}]}`,
+			want: []string{`{"name":"a"}`, `{"name":"b"}`},
+		},
+		{
+			name: "other members are skipped",
+			body: `{"meta":{"results":[1]},"results":[{"name":"a"}],"status":"ok"}`,
+			want: []string{`{"name":"a"}`},
+		},
+		{
+			name: "empty results",
+			body: `{"results":[]}`,
+			want: nil,
+		},
+		{
+			name:    "not an object",
+			body:    `[{"name":"a"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "truncated",
+			body:    `{"results":[{"name":"a"},{"na`,
+			want:    []string{`{"name":"a"}`},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			err := decodeResults(strings.NewReader(tt.body), func(raw json.RawMessage) error {
+				got = append(got, string(raw))
+				return nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeResults() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("decodeResults() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// serviceStream generates a response with n services, without holding it in memory.
+// Closing the stream stops the generation.
+func serviceStream(n int) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		_, _ = io.WriteString(pw, `{"results":[`)
+		for i := 0; i < n; i++ {
+			if i > 0 {
+				_, _ = io.WriteString(pw, ",")
+			}
+			_, _ = fmt.Fprintf(pw, `{"name":"host!svc-%d","type":"Service","attrs":{"host_name":"host","state":%d.0}}`, i, i%4)
+		}
+		_, _ = io.WriteString(pw, `]}`)
+		_ = pw.Close()
+	}()
+	return pr
+}
+
+func Test_services_Stream(t *testing.T) {
+	c := services{ic: newTestClient()}
+	c.ic.Config.Retry = DefaultRetryPolicy()
+	httpmock.ActivateNonDefault(c.ic.Client)
+	defer httpmock.DeactivateAndReset()
+
+	url := c.ic.Config.BaseURL + "/objects/services"
+	const n = 10000
+	httpmock.RegisterResponder(http.MethodGet, url, func(req *http.Request) (*http.Response, error) {
+		resp := httpmock.NewStringResponse(http.StatusOK, "")
+		resp.Body = serviceStream(n)
+		return resp, nil
+	})
+
+	var count int
+	var critical int
+	err := c.Stream(context.Background(), nil, func(svc *Service) error {
+		count++
+		if svc.State == ServiceCritical {
+			critical++
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	if count != n || critical != n/4 {
+		t.Errorf("Stream() decoded %d services, %d critical, want %d and %d", count, critical, n, n/4)
+	}
+
+	// an error of the callback stops the stream and is neither retried nor failed over
+	stop := errors.New("stop")
+	httpmock.ZeroCallCounters()
+	count = 0
+	err = c.Stream(context.Background(), nil, func(svc *Service) error {
+		count++
+		if count == 10 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) || count != 10 {
+		t.Errorf("Stream() error = %v after %d services, want the callback error after 10", err, count)
+	}
+	if calls := httpmock.GetTotalCallCount(); calls != 1 {
+		t.Errorf("Stream() sent %d requests, want 1", calls)
+	}
+}
+
+func Test_hosts_List(t *testing.T) {
+	c := hosts{ic: newTestClient()}
+	httpmock.ActivateNonDefault(c.ic.Client)
+	defer httpmock.DeactivateAndReset()
+
+	var gotQuery ObjectQuery
+	httpmock.RegisterResponder(http.MethodGet, c.ic.Config.BaseURL+"/objects/hosts",
+		func(req *http.Request) (*http.Response, error) {
+			if err := json.NewDecoder(req.Body).Decode(&gotQuery); err != nil {
+				return nil, err
+			}
+			return httpmock.NewStringResponse(http.StatusOK, `{"results":[`+testHostQueryResult()+`]}`), nil
+		})
+
+	got, err := c.List(context.Background(), &ObjectQuery{Filter: `host.name=="test-host"`})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(got) != 1 || got[0].Name != "test-host" || got[0].Address != "localhost" {
+		t.Errorf("List() = %v, want the test host", got)
+	}
+	if gotQuery.Filter != `host.name=="test-host"` {
+		t.Errorf("List() sent filter %q", gotQuery.Filter)
+	}
+
+	httpmock.RegisterResponder(http.MethodGet, c.ic.Config.BaseURL+"/objects/hosts",
+		httpmock.NewStringResponder(http.StatusNotFound, `{"error":404,"status":"No objects found."}`))
+	if _, err := c.List(context.Background(), nil); !IsNotFound(err) {
+		t.Errorf("List() error = %v, want not found", err)
+	}
+}
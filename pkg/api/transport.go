@@ -0,0 +1,121 @@
+package api
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/puffitos/goicinga/internal/util"
+)
+
+const (
+	defaultMaxIdleConns        = 100
+	defaultMaxIdleConnsPerHost = 10
+	defaultIdleConnTimeout     = 90 * time.Second
+	defaultKeepAlive           = 30 * time.Second
+	defaultDialTimeout         = 30 * time.Second
+	defaultTLSHandshakeTimeout = 10 * time.Second
+)
+
+// TransportConfig tunes the connection pooling of the http.Transport used by the icinga client.
+// Zero values fall back to the defaults of http.DefaultTransport, except for
+// MaxIdleConnsPerHost, which defaults to 10 as all connections go to the same host.
+type TransportConfig struct {
+	// MaxIdleConns is the maximum number of idle connections across all hosts.
+	MaxIdleConns int
+	// MaxIdleConnsPerHost is the maximum number of idle connections kept per host.
+	MaxIdleConnsPerHost int
+	// MaxConnsPerHost limits the number of connections per host. Zero means no limit.
+	MaxConnsPerHost int
+	// IdleConnTimeout is the time an idle connection is kept open.
+	IdleConnTimeout time.Duration
+	// KeepAlive is the interval of the TCP keep-alive probes.
+	KeepAlive time.Duration
+	// TLSHandshakeTimeout is the maximum time to wait for a TLS handshake.
+	TLSHandshakeTimeout time.Duration
+	// DisableKeepAlives disables connection reuse between requests.
+	DisableKeepAlives bool
+	// DisableHTTP2 prevents the client from negotiating HTTP/2 with the icinga API.
+	DisableHTTP2 bool
+}
+
+// newTransport creates the round tripper used by the icinga client. A RoundTripper
+// supplied in the config is returned as is.
+func newTransport(config *Config) (http.RoundTripper, error) {
+	if config.RoundTripper != nil {
+		return config.RoundTripper, nil
+	}
+
+	tlsConfig, err := util.NewTLSConfig(config.tlsOptions())
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS configuration: %w", err)
+	}
+
+	tc := config.Transport
+	dialer := &net.Dialer{
+		Timeout:   defaultDialTimeout,
+		KeepAlive: durationOrDefault(tc.KeepAlive, defaultKeepAlive),
+	}
+	t := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		DialContext:         dialer.DialContext,
+		TLSClientConfig:     tlsConfig,
+		ForceAttemptHTTP2:   !tc.DisableHTTP2,
+		MaxIdleConns:        intOrDefault(tc.MaxIdleConns, defaultMaxIdleConns),
+		MaxIdleConnsPerHost: intOrDefault(tc.MaxIdleConnsPerHost, defaultMaxIdleConnsPerHost),
+		MaxConnsPerHost:     tc.MaxConnsPerHost,
+		IdleConnTimeout:     durationOrDefault(tc.IdleConnTimeout, defaultIdleConnTimeout),
+		TLSHandshakeTimeout: durationOrDefault(tc.TLSHandshakeTimeout, defaultTLSHandshakeTimeout),
+		DisableKeepAlives:   tc.DisableKeepAlives,
+	}
+	if tc.DisableHTTP2 {
+		// a non-nil, empty map disables the automatic HTTP/2 upgrade
+		t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	if config.ReloadCertificates && config.ServerName == "" {
+		// the TLS config does not know the dialed host, which the verification against
+		// the reloaded CA needs for hosts given as IP address
+		nextProtos := []string{"h2", "http/1.1"}
+		if tc.DisableHTTP2 {
+			nextProtos = []string{"http/1.1"}
+		}
+		dialTLS, err := util.NewTLSDialer(config.tlsOptions(), nextProtos, dialer.DialContext)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TLS configuration: %w", err)
+		}
+		t.DialTLSContext = dialTLS
+	}
+	return t, nil
+}
+
+// tlsOptions returns the TLS settings of the config.
+func (c *Config) tlsOptions() util.TLSOptions {
+	return util.TLSOptions{
+		CAPath:         c.CertPath,
+		ClientCertPath: c.ClientCertPath,
+		ClientKeyPath:  c.ClientKeyPath,
+		ClientCertPEM:  c.ClientCertPEM,
+		ClientKeyPEM:   c.ClientKeyPEM,
+
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		ServerName:         c.ServerName,
+		PinnedFingerprints: c.PinnedFingerprints,
+		Reload:             c.ReloadCertificates,
+	}
+}
+
+func intOrDefault(v, def int) int {
+	if v == 0 {
+		return def
+	}
+	return v
+}
+
+func durationOrDefault(v, def time.Duration) time.Duration {
+	if v == 0 {
+		return def
+	}
+	return v
+}
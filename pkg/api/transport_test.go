@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/jarcoal/httpmock"
+)
+
+func Test_newTransport(t *testing.T) {
+	tests := []struct {
+		name                    string
+		config                  TransportConfig
+		wantMaxIdleConnsPerHost int
+		wantIdleConnTimeout     time.Duration
+		wantHTTP2               bool
+	}{
+		{
+			name:                    "defaults",
+			config:                  TransportConfig{},
+			wantMaxIdleConnsPerHost: defaultMaxIdleConnsPerHost,
+			wantIdleConnTimeout:     defaultIdleConnTimeout,
+			wantHTTP2:               true,
+		},
+		{
+			name: "tuned",
+			config: TransportConfig{
+				MaxIdleConnsPerHost: 50,
+				IdleConnTimeout:     time.Minute,
+				DisableHTTP2:        true,
+			},
+			wantMaxIdleConnsPerHost: 50,
+			wantIdleConnTimeout:     time.Minute,
+			wantHTTP2:               false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt, err := newTransport(&Config{Transport: tt.config})
+			if err != nil {
+				t.Fatalf("newTransport() error = %v", err)
+			}
+			tr, ok := rt.(*http.Transport)
+			if !ok {
+				t.Fatalf("newTransport() did not return an *http.Transport")
+			}
+			if tr.MaxIdleConnsPerHost != tt.wantMaxIdleConnsPerHost {
+				t.Errorf("MaxIdleConnsPerHost = %d, want %d", tr.MaxIdleConnsPerHost, tt.wantMaxIdleConnsPerHost)
+			}
+			if tr.IdleConnTimeout != tt.wantIdleConnTimeout {
+				t.Errorf("IdleConnTimeout = %v, want %v", tr.IdleConnTimeout, tt.wantIdleConnTimeout)
+			}
+			if gotHTTP2 := tr.TLSNextProto == nil; gotHTTP2 != tt.wantHTTP2 {
+				t.Errorf("HTTP/2 enabled = %v, want %v", gotHTTP2, tt.wantHTTP2)
+			}
+		})
+	}
+}
+
+func TestNewClientSet_RoundTripper(t *testing.T) {
+	rt := httpmock.NewMockTransport()
+	rt.RegisterResponder(http.MethodGet, "https://icinga-server:5665/v1/objects/hosts/test",
+		httpmock.NewStringResponder(http.StatusOK, `{"results":[`+testHostQueryResult()+`]}`))
+	rt.RegisterResponder(http.MethodGet, "https://icinga-server:5665/v1/objects/services/test",
+		httpmock.NewStringResponder(http.StatusOK, `{"results":[`+testServiceQueryResult()+`]}`))
+
+	cs, err := NewClientSet(&Config{BaseURL: "https://icinga-server:5665/v1", RoundTripper: rt}, nil)
+	if err != nil {
+		t.Fatalf("NewClientSet() error = %v", err)
+	}
+	if _, err := cs.Hosts().Get(context.Background(), "test"); err != nil {
+		t.Fatalf("Hosts().Get() error = %v", err)
+	}
+	if _, err := cs.Services().Get(context.Background(), "test"); err != nil {
+		t.Fatalf("Services().Get() error = %v", err)
+	}
+	if got := rt.GetTotalCallCount(); got != 2 {
+		t.Errorf("RoundTripper called %d times, want 2", got)
+	}
+}
+
+func TestNew_ReloadCertificatesIP(t *testing.T) {
+	var proto int
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proto = r.ProtoMajor
+		_, _ = io.WriteString(w, `{"results":[]}`)
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	caPath := filepath.Join(t.TempDir(), "ca.crt")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caPath, ca, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// the BaseURL has an IP host and no server name is set
+	l := logr.Discard()
+	c, err := New(&Config{BaseURL: srv.URL + "/v1", CertPath: caPath, ReloadCertificates: true}, &l)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := c.Get().Endpoint("objects").Type("hosts").Call(context.Background()).Error(); err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+	if proto != 2 {
+		t.Errorf("request used HTTP/%d, want HTTP/2", proto)
+	}
+}
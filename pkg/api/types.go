@@ -0,0 +1,100 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strconv"
+	"time"
+)
+
+// IcingaTime is a point in time, which the icinga API encodes as UNIX timestamp in
+// (fractional) seconds. The zero time is encoded as 0, which icinga uses for "never".
+// As a struct, omitempty has no effect on it; optional fields use *IcingaTime instead.
+type IcingaTime struct {
+	time.Time
+}
+
+// NewIcingaTime returns the IcingaTime for the given time.
+func NewIcingaTime(t time.Time) IcingaTime {
+	return IcingaTime{Time: t}
+}
+
+// icingaTimeFromSeconds returns the IcingaTime for the given UNIX timestamp in seconds.
+func icingaTimeFromSeconds(s float64) IcingaTime {
+	if s == 0 {
+		return IcingaTime{}
+	}
+	seconds := int64(s)
+	nanoseconds := int64((s - float64(seconds)) * Ms)
+	return IcingaTime{Time: time.Unix(seconds, nanoseconds).UTC()}
+}
+
+// Seconds returns the UNIX timestamp in seconds, or 0 for the zero time.
+func (t IcingaTime) Seconds() float64 {
+	if t.IsZero() {
+		return 0
+	}
+	return float64(t.UnixNano()) / Ms
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (t IcingaTime) MarshalJSON() ([]byte, error) {
+	return strconv.AppendFloat(nil, t.Seconds(), 'f', -1, 64), nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (t *IcingaTime) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		*t = IcingaTime{}
+		return nil
+	}
+	var s float64
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*t = icingaTimeFromSeconds(s)
+	return nil
+}
+
+// IcingaDuration is a duration, which the icinga API encodes as (fractional) seconds.
+type IcingaDuration time.Duration
+
+// icingaDurationFromSeconds returns the IcingaDuration for the given number of seconds.
+func icingaDurationFromSeconds(s float64) IcingaDuration {
+	return IcingaDuration(math.Round(s * float64(time.Second)))
+}
+
+// Duration returns the duration as time.Duration.
+func (d IcingaDuration) Duration() time.Duration {
+	return time.Duration(d)
+}
+
+// Seconds returns the duration in seconds.
+func (d IcingaDuration) Seconds() float64 {
+	return time.Duration(d).Seconds()
+}
+
+// String returns the duration formatted like a time.Duration.
+func (d IcingaDuration) String() string {
+	return time.Duration(d).String()
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (d IcingaDuration) MarshalJSON() ([]byte, error) {
+	return strconv.AppendFloat(nil, d.Seconds(), 'f', -1, 64), nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (d *IcingaDuration) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		*d = 0
+		return nil
+	}
+	var s float64
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*d = icingaDurationFromSeconds(s)
+	return nil
+}
@@ -0,0 +1,194 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestIcingaTime_JSON(t *testing.T) {
+	tests := []struct {
+		name string
+		time IcingaTime
+		want string
+	}{
+		{
+			name: "zero",
+			time: IcingaTime{},
+			want: "0",
+		},
+		{
+			name: "seconds",
+			time: NewIcingaTime(time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)),
+			want: "1583020800",
+		},
+		{
+			name: "fractional seconds",
+			time: NewIcingaTime(time.Date(2020, 3, 1, 0, 0, 0, 500000000, time.UTC)),
+			want: "1583020800.5",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := json.Marshal(tt.time)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if string(b) != tt.want {
+				t.Errorf("Marshal() = %s, want %s", b, tt.want)
+			}
+
+			var got IcingaTime
+			if err := json.Unmarshal(b, &got); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if !got.Equal(tt.time.Time) {
+				t.Errorf("Unmarshal() = %v, want %v", got, tt.time)
+			}
+		})
+	}
+}
+
+func TestIcingaDuration_JSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		duration IcingaDuration
+		want     string
+	}{
+		{
+			name:     "zero",
+			duration: 0,
+			want:     "0",
+		},
+		{
+			name:     "minutes",
+			duration: IcingaDuration(5 * time.Minute),
+			want:     "300",
+		},
+		{
+			name:     "fractional seconds",
+			duration: IcingaDuration(1500 * time.Millisecond),
+			want:     "1.5",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := json.Marshal(tt.duration)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if string(b) != tt.want {
+				t.Errorf("Marshal() = %s, want %s", b, tt.want)
+			}
+
+			var got IcingaDuration
+			if err := json.Unmarshal(b, &got); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if got != tt.duration {
+				t.Errorf("Unmarshal() = %v, want %v", got, tt.duration)
+			}
+		})
+	}
+}
+
+func TestCheckableAttrs_JSON(t *testing.T) {
+	attrs := CheckableAttrs{
+		CheckInterval:         IcingaDuration(5 * time.Minute),
+		RetryInterval:         IcingaDuration(30 * time.Second),
+		AcknowledgementExpiry: NewIcingaTime(time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)),
+	}
+	b, err := json.Marshal(attrs)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]float64{
+		"check_interval":         300,
+		"retry_interval":         30,
+		"acknowledgement_expiry": 1583020800,
+		"last_check":             0,
+	}
+	for k, v := range want {
+		if raw[k] != v {
+			t.Errorf("%s = %v, want %v", k, raw[k], v)
+		}
+	}
+	if _, ok := raw["check_timeout"]; ok {
+		t.Errorf("check_timeout is set, want it omitted")
+	}
+
+	var got CheckableAttrs
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if got.CheckInterval != attrs.CheckInterval || got.RetryInterval != attrs.RetryInterval ||
+		!got.AcknowledgementExpiry.Equal(attrs.AcknowledgementExpiry.Time) {
+		t.Errorf("Unmarshal() = %+v, want %+v", got, attrs)
+	}
+}
+
+func TestIcingaTime_MarshalFields(t *testing.T) {
+	tests := []struct {
+		name        string
+		obj         interface{}
+		wantZero    []string
+		wantOmitted []string
+	}{
+		{
+			name:     "check result",
+			obj:      CheckResult{},
+			wantZero: []string{"schedule_start", "schedule_end", "execution_start", "execution_end"},
+		},
+		{
+			name:     "host",
+			obj:      Host{},
+			wantZero: []string{"last_state_down", "last_state_up", "last_check", "next_check"},
+		},
+		{
+			name:        "optional times",
+			obj:         UpdateCheckOutputRequest{},
+			wantOmitted: []string{"execution_start", "execution_end"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := json.Marshal(tt.obj)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			var raw map[string]interface{}
+			if err := json.Unmarshal(b, &raw); err != nil {
+				t.Fatal(err)
+			}
+			for _, k := range tt.wantZero {
+				if v, ok := raw[k]; !ok || v != 0.0 {
+					t.Errorf("%s = %v, want 0", k, v)
+				}
+			}
+			for _, k := range tt.wantOmitted {
+				if v, ok := raw[k]; ok {
+					t.Errorf("%s = %v, want it omitted", k, v)
+				}
+			}
+		})
+	}
+}
+
+func TestHost_UnmarshalJSON_Durations(t *testing.T) {
+	data := `{"name":"test","type":"Host","attrs":{"check_interval":300,"retry_interval":60.5,"last_check":1583020800.25}}`
+	var h Host
+	if err := json.Unmarshal([]byte(data), &h); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if h.CheckInterval.Duration() != 5*time.Minute || h.RetryInterval.Duration() != 60500*time.Millisecond {
+		t.Errorf("intervals = %v, %v, want 5m0s, 1m0.5s", h.CheckInterval, h.RetryInterval)
+	}
+	if want := time.Date(2020, 3, 1, 0, 0, 0, 250000000, time.UTC); !h.LastCheck.Equal(want) {
+		t.Errorf("LastCheck = %v, want %v", h.LastCheck, want)
+	}
+}
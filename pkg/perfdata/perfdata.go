@@ -0,0 +1,265 @@
+// Package perfdata parses and renders performance data in the Nagios plugin format,
+// as submitted with check results to the icinga API:
+//
+//	'label'=value[UOM];[warn];[crit];[min];[max]
+package perfdata
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Value is a single performance data value.
+type Value struct {
+	// Label is the name of the value. It may contain any character but '=', quotes are escaped on rendering.
+	Label string
+	// Value is the measured value. It is meaningless if Unknown is set.
+	Value float64
+	// Unknown is set if the value could not be determined, rendered as "U".
+	Unknown bool
+	// UOM is the unit of measurement, e.g. "s", "%", "B" or "c".
+	UOM string
+	// Warn is the warning threshold, nil if there is none.
+	Warn *Range
+	// Crit is the critical threshold, nil if there is none.
+	Crit *Range
+	// Min is the minimum possible value, nil if there is none.
+	Min *float64
+	// Max is the maximum possible value, nil if there is none.
+	Max *float64
+}
+
+// New returns the value with the given label, value and unit of measurement.
+func New(label string, value float64, uom string) Value {
+	return Value{Label: label, Value: value, UOM: uom}
+}
+
+// WithWarn returns the value with the given warning threshold.
+func (v Value) WithWarn(r Range) Value {
+	v.Warn = &r
+	return v
+}
+
+// WithCrit returns the value with the given critical threshold.
+func (v Value) WithCrit(r Range) Value {
+	v.Crit = &r
+	return v
+}
+
+// WithMin returns the value with the given minimum.
+func (v Value) WithMin(min float64) Value {
+	v.Min = &min
+	return v
+}
+
+// WithMax returns the value with the given maximum.
+func (v Value) WithMax(max float64) Value {
+	v.Max = &max
+	return v
+}
+
+// Parse parses the space separated performance data values of a plugin output, i.e. the part after the '|'.
+func Parse(s string) ([]Value, error) {
+	var values []Value
+	rest := strings.TrimSpace(s)
+	for rest != "" {
+		token, next, err := nextToken(rest)
+		if err != nil {
+			return values, err
+		}
+		v, err := ParseValue(token)
+		if err != nil {
+			return values, err
+		}
+		values = append(values, v)
+		rest = strings.TrimLeft(next, " \t\r\n")
+	}
+	return values, nil
+}
+
+// nextToken splits s at the first whitespace, which is not part of a quoted label.
+func nextToken(s string) (token, rest string, err error) {
+	i := 0
+	if s[0] == '\'' {
+		end := quotedEnd(s)
+		if end < 0 {
+			return "", "", fmt.Errorf("invalid perfdata %q: unterminated label", s)
+		}
+		i = end + 1
+	}
+	if j := strings.IndexAny(s[i:], " \t\r\n"); j >= 0 {
+		return s[:i+j], s[i+j:], nil
+	}
+	return s, "", nil
+}
+
+// quotedEnd returns the index of the quote ending the quoted label s starts with,
+// or -1 if it is unterminated. Quotes in the label are escaped by doubling them.
+func quotedEnd(s string) int {
+	for i := 1; i < len(s); i++ {
+		if s[i] != '\'' {
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == '\'' {
+			i++
+			continue
+		}
+		return i
+	}
+	return -1
+}
+
+// ParseValue parses a single performance data value.
+func ParseValue(s string) (Value, error) {
+	var v Value
+	var rest string
+	if strings.HasPrefix(s, "'") {
+		end := quotedEnd(s)
+		if end < 0 {
+			return Value{}, fmt.Errorf("invalid perfdata %q: unterminated label", s)
+		}
+		v.Label = strings.ReplaceAll(s[1:end], "''", "'")
+		rest = s[end+1:]
+		if !strings.HasPrefix(rest, "=") {
+			return Value{}, fmt.Errorf("invalid perfdata %q: missing '=' after label", s)
+		}
+		rest = rest[1:]
+	} else {
+		i := strings.IndexByte(s, '=')
+		if i < 0 {
+			return Value{}, fmt.Errorf("invalid perfdata %q: missing '='", s)
+		}
+		v.Label, rest = s[:i], s[i+1:]
+	}
+	if v.Label == "" {
+		return Value{}, fmt.Errorf("invalid perfdata %q: empty label", s)
+	}
+
+	fields := strings.Split(rest, ";")
+	if len(fields) > 5 {
+		return Value{}, fmt.Errorf("invalid perfdata %q: too many fields", s)
+	}
+	if err := v.parseValue(fields[0]); err != nil {
+		return Value{}, fmt.Errorf("invalid perfdata %q: %w", s, err)
+	}
+	for i, f := range fields[1:] {
+		if f == "" {
+			continue
+		}
+		var err error
+		switch i {
+		case 0:
+			v.Warn, err = parseRangeField(f)
+		case 1:
+			v.Crit, err = parseRangeField(f)
+		case 2:
+			v.Min, err = parseNumberField(f)
+		case 3:
+			v.Max, err = parseNumberField(f)
+		}
+		if err != nil {
+			return Value{}, fmt.Errorf("invalid perfdata %q: %w", s, err)
+		}
+	}
+	return v, nil
+}
+
+// parseValue parses the value and its unit of measurement.
+func (v *Value) parseValue(s string) error {
+	if s == "U" {
+		v.Unknown = true
+		return nil
+	}
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return !strings.ContainsRune("+-.0123456789", r)
+	})
+	if i < 0 {
+		i = len(s)
+	}
+	if i == 0 {
+		return fmt.Errorf("missing value")
+	}
+	f, err := parseNumber(s[:i])
+	if err != nil {
+		return err
+	}
+	v.Value, v.UOM = f, s[i:]
+	return nil
+}
+
+func parseRangeField(s string) (*Range, error) {
+	r, err := ParseRange(s)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func parseNumberField(s string) (*float64, error) {
+	f, err := parseNumber(s)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// String returns the value in the Nagios plugin format. Trailing empty fields are omitted.
+func (v Value) String() string {
+	var b strings.Builder
+	b.WriteString(formatLabel(v.Label))
+	b.WriteByte('=')
+	if v.Unknown {
+		b.WriteByte('U')
+	} else {
+		b.WriteString(formatNumber(v.Value))
+		b.WriteString(v.UOM)
+	}
+
+	fields := make([]string, 4)
+	if v.Warn != nil {
+		fields[0] = v.Warn.String()
+	}
+	if v.Crit != nil {
+		fields[1] = v.Crit.String()
+	}
+	if v.Min != nil {
+		fields[2] = formatNumber(*v.Min)
+	}
+	if v.Max != nil {
+		fields[3] = formatNumber(*v.Max)
+	}
+	n := len(fields)
+	for n > 0 && fields[n-1] == "" {
+		n--
+	}
+	for _, f := range fields[:n] {
+		b.WriteByte(';')
+		b.WriteString(f)
+	}
+	return b.String()
+}
+
+// formatLabel quotes the label if it contains whitespace, quotes or '='.
+func formatLabel(label string) string {
+	if !strings.ContainsAny(label, " \t'=") {
+		return label
+	}
+	return "'" + strings.ReplaceAll(label, "'", "''") + "'"
+}
+
+// Strings renders the values for submission, e.g. as UpdateCheckOutputRequest.PerformanceData.
+func Strings(values []Value) []string {
+	if values == nil {
+		return nil
+	}
+	s := make([]string, len(values))
+	for i := range values {
+		s[i] = values[i].String()
+	}
+	return s
+}
+
+// Format renders the values as space separated performance data, e.g. to append to a plugin output after '|'.
+func Format(values []Value) string {
+	return strings.Join(Strings(values), " ")
+}
@@ -0,0 +1,122 @@
+package perfdata
+
+import (
+	"reflect"
+	"testing"
+)
+
+func ptr(f float64) *float64 {
+	return &f
+}
+
+func rng(s string) *Range {
+	r, err := ParseRange(s)
+	if err != nil {
+		panic(err)
+	}
+	return &r
+}
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    Value
+		wantErr bool
+	}{
+		{name: "value", s: "load1=0.5", want: Value{Label: "load1", Value: 0.5}},
+		{name: "uom", s: "time=12.5ms", want: Value{Label: "time", Value: 12.5, UOM: "ms"}},
+		{
+			name: "all fields",
+			s:    "disk=80%;@70:90;95;0;100",
+			want: Value{Label: "disk", Value: 80, UOM: "%", Warn: rng("@70:90"), Crit: rng("95"), Min: ptr(0), Max: ptr(100)},
+		},
+		{name: "empty fields", s: "rx=10c;;;0;", want: Value{Label: "rx", Value: 10, UOM: "c", Min: ptr(0)}},
+		{name: "quoted label", s: "'free space /'=1KB", want: Value{Label: "free space /", Value: 1, UOM: "KB"}},
+		{name: "escaped quote", s: "'it''s=fine'=1", want: Value{Label: "it's=fine", Value: 1}},
+		{name: "unknown", s: "temp=U;10;20", want: Value{Label: "temp", Unknown: true, Warn: rng("10"), Crit: rng("20")}},
+		{name: "negative", s: "offset=-0.25s", want: Value{Label: "offset", Value: -0.25, UOM: "s"}},
+		{name: "missing equals", s: "load1", wantErr: true},
+		{name: "empty label", s: "=1", wantErr: true},
+		{name: "missing value", s: "load1=", wantErr: true},
+		{name: "invalid value", s: "load1=1.2.3", wantErr: true},
+		{name: "unterminated label", s: "'load1=1", wantErr: true},
+		{name: "invalid range", s: "load1=1;a", wantErr: true},
+		{name: "invalid max", s: "load1=1;;;;x", wantErr: true},
+		{name: "too many fields", s: "load1=1;1;1;1;1;1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseValue(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseValue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseValue() got = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	got, err := Parse(" load1=0.5;1;2 'free space /'=10GB;;;0  time=1s\n")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := []Value{
+		{Label: "load1", Value: 0.5, Warn: rng("1"), Crit: rng("2")},
+		{Label: "free space /", Value: 10, UOM: "GB", Min: ptr(0)},
+		{Label: "time", Value: 1, UOM: "s"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse() got = %+v, want %+v", got, want)
+	}
+
+	if _, err := Parse("load1=0.5 broken"); err == nil {
+		t.Errorf("Parse() error = nil, want an error for an invalid value")
+	}
+	if got, err := Parse(""); err != nil || got != nil {
+		t.Errorf("Parse() = %v, %v, want no values", got, err)
+	}
+}
+
+func TestValue_String(t *testing.T) {
+	tests := []struct {
+		name  string
+		value Value
+		want  string
+	}{
+		{name: "value", value: New("load1", 0.5, ""), want: "load1=0.5"},
+		{
+			name:  "all fields",
+			value: New("disk", 80, "%").WithWarn(Range{Start: 70, End: 90, Inside: true}).WithCrit(NewRange(0, 95)).WithMin(0).WithMax(100),
+			want:  "disk=80%;@70:90;95;0;100",
+		},
+		{name: "empty fields", value: New("rx", 10, "c").WithMin(0), want: "rx=10c;;;0"},
+		{name: "quoted label", value: New("free space /", 1, "KB"), want: "'free space /'=1KB"},
+		{name: "escaped quote", value: New("it's", 1, ""), want: "'it''s'=1"},
+		{name: "unknown", value: Value{Label: "temp", Unknown: true}, want: "temp=U"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.value.String()
+			if got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+			parsed, err := ParseValue(got)
+			if err != nil || !reflect.DeepEqual(parsed, tt.value) {
+				t.Errorf("ParseValue(String()) = %+v, %v, want %+v", parsed, err, tt.value)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	values := []Value{New("a", 1, "s"), New("b c", 2, "")}
+	if got, want := Format(values), "a=1s 'b c'=2"; got != want {
+		t.Errorf("Format() = %q, want %q", got, want)
+	}
+	if Strings(nil) != nil {
+		t.Errorf("Strings(nil) != nil")
+	}
+}
@@ -0,0 +1,116 @@
+package perfdata
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Range is a threshold range in the Nagios plugin format, e.g. "10", "10:", "~:10", "10:20" or "@10:20".
+// Start and End are inclusive, unbounded ends are infinite.
+type Range struct {
+	// Start is the lower bound of the range, math.Inf(-1) if it is unbounded.
+	Start float64
+	// End is the upper bound of the range, math.Inf(1) if it is unbounded.
+	End float64
+	// Inside inverts the range, i.e. values inside of it raise an alert instead of values outside of it.
+	Inside bool
+}
+
+// NewRange returns the range from start to end, which alerts for values outside of it.
+func NewRange(start, end float64) Range {
+	return Range{Start: start, End: end}
+}
+
+// ParseRange parses a range in the Nagios plugin format:
+//
+//	10      alert if < 0 or > 10
+//	10:     alert if < 10
+//	~:10    alert if > 10
+//	10:20   alert if < 10 or > 20
+//	@10:20  alert if >= 10 and <= 20
+func ParseRange(s string) (Range, error) {
+	r := Range{Start: 0, End: math.Inf(1)}
+	rest := s
+	if strings.HasPrefix(rest, "@") {
+		r.Inside = true
+		rest = rest[1:]
+	}
+	if rest == "" {
+		return Range{}, fmt.Errorf("invalid range %q: empty", s)
+	}
+
+	end := rest
+	if i := strings.IndexByte(rest, ':'); i >= 0 {
+		start := rest[:i]
+		end = rest[i+1:]
+		switch start {
+		case "~":
+			r.Start = math.Inf(-1)
+		case "":
+		default:
+			f, err := parseNumber(start)
+			if err != nil {
+				return Range{}, fmt.Errorf("invalid range %q: %w", s, err)
+			}
+			r.Start = f
+		}
+	}
+	if end != "" {
+		f, err := parseNumber(end)
+		if err != nil {
+			return Range{}, fmt.Errorf("invalid range %q: %w", s, err)
+		}
+		r.End = f
+	}
+	if r.Start > r.End {
+		return Range{}, fmt.Errorf("invalid range %q: start is greater than end", s)
+	}
+	return r, nil
+}
+
+// Contains reports whether the value is within the range, ignoring Inside.
+func (r Range) Contains(v float64) bool {
+	return v >= r.Start && v <= r.End
+}
+
+// Alerts reports whether the value raises an alert, i.e. it is outside of the range,
+// or inside of it if the range is inverted. NaN always raises an alert.
+func (r Range) Alerts(v float64) bool {
+	if math.IsNaN(v) {
+		return true
+	}
+	return r.Contains(v) == r.Inside
+}
+
+// String returns the range in the Nagios plugin format.
+func (r Range) String() string {
+	var b strings.Builder
+	if r.Inside {
+		b.WriteByte('@')
+	}
+	switch {
+	case math.IsInf(r.Start, -1):
+		b.WriteString("~:")
+	case r.Start != 0 || math.IsInf(r.End, 1):
+		b.WriteString(formatNumber(r.Start))
+		b.WriteByte(':')
+	}
+	if !math.IsInf(r.End, 1) {
+		b.WriteString(formatNumber(r.End))
+	}
+	return b.String()
+}
+
+func parseNumber(s string) (float64, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	return f, nil
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
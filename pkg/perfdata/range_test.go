@@ -0,0 +1,91 @@
+package perfdata
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseRange(t *testing.T) {
+	inf := math.Inf(1)
+	tests := []struct {
+		name    string
+		s       string
+		want    Range
+		wantErr bool
+	}{
+		{name: "end", s: "10", want: Range{Start: 0, End: 10}},
+		{name: "start", s: "10:", want: Range{Start: 10, End: inf}},
+		{name: "negative infinity", s: "~:10", want: Range{Start: -inf, End: 10}},
+		{name: "start and end", s: "10:20", want: Range{Start: 10, End: 20}},
+		{name: "inside", s: "@10:20", want: Range{Start: 10, End: 20, Inside: true}},
+		{name: "negative", s: "-5.5:-1", want: Range{Start: -5.5, End: -1}},
+		{name: "unbounded", s: "~:", want: Range{Start: -inf, End: inf}},
+		{name: "empty", s: "", wantErr: true},
+		{name: "only inside", s: "@", wantErr: true},
+		{name: "start greater than end", s: "20:10", wantErr: true},
+		{name: "not a number", s: "a:b", wantErr: true},
+		{name: "infinite number", s: "inf", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRange(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseRange() got = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRange_String(t *testing.T) {
+	for _, s := range []string{"10", "10:", "~:10", "10:20", "@10:20", "@~:0", "~:", "-1:1", "0.5"} {
+		t.Run(s, func(t *testing.T) {
+			r, err := ParseRange(s)
+			if err != nil {
+				t.Fatalf("ParseRange() error = %v", err)
+			}
+			if got := r.String(); got != s {
+				t.Errorf("String() = %q, want %q", got, s)
+			}
+		})
+	}
+	if got := NewRange(0, math.Inf(1)).String(); got != "0:" {
+		t.Errorf("String() = %q, want %q", got, "0:")
+	}
+}
+
+func TestRange_Alerts(t *testing.T) {
+	tests := []struct {
+		r     string
+		value float64
+		want  bool
+	}{
+		{r: "10", value: -1, want: true},
+		{r: "10", value: 0, want: false},
+		{r: "10", value: 10, want: false},
+		{r: "10", value: 10.1, want: true},
+		{r: "10:", value: 9, want: true},
+		{r: "10:", value: 1e9, want: false},
+		{r: "~:10", value: -1e9, want: false},
+		{r: "~:10", value: 11, want: true},
+		{r: "10:20", value: 9.9, want: true},
+		{r: "10:20", value: 15, want: false},
+		{r: "10:20", value: 21, want: true},
+		{r: "@10:20", value: 10, want: true},
+		{r: "@10:20", value: 20, want: true},
+		{r: "@10:20", value: 9, want: false},
+		{r: "@10:20", value: 21, want: false},
+		{r: "10", value: math.NaN(), want: true},
+	}
+	for _, tt := range tests {
+		r, err := ParseRange(tt.r)
+		if err != nil {
+			t.Fatalf("ParseRange(%q) error = %v", tt.r, err)
+		}
+		if got := r.Alerts(tt.value); got != tt.want {
+			t.Errorf("%q.Alerts(%v) = %v, want %v", tt.r, tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/puffitos/goicinga/pkg/api"
+	"github.com/puffitos/goicinga/pkg/perfdata"
+)
+
+// Command returns a check executing an external plugin command, e.g. one of the monitoring plugins.
+// The state is taken from the exit code of the command and its output is parsed with ParseOutput.
+// The command is killed once the context is done.
+func Command(name string, args ...string) Check {
+	return func(ctx context.Context, res *Result) error {
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, name, args...)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+
+		err := cmd.Run()
+		var exitErr *exec.ExitError
+		if err != nil && !errors.As(err, &exitErr) {
+			return fmt.Errorf("failed to execute %s: %w", name, err)
+		}
+
+		out := stdout.String()
+		if strings.TrimSpace(out) == "" {
+			out = stderr.String()
+		}
+		*res = ParseOutput(out)
+		res.State = api.ServiceState(cmd.ProcessState.ExitCode())
+		if _, ok := stateNames[res.State]; !ok {
+			res.State = api.ServiceUnknown
+		}
+		if res.Summary == "" {
+			res.Summary = fmt.Sprintf("%s exited with code %d without output", name, cmd.ProcessState.ExitCode())
+		}
+		return nil
+	}
+}
+
+// ParseOutput parses the output of a plugin: the summary and performance data on the first line,
+// separated by '|', followed by the long output. Further performance data may follow a '|' in
+// the long output. The summary is kept as is, and invalid performance data is reported in the
+// long output. The state of the result is OK, as it is only known from the exit code.
+func ParseOutput(output string) Result {
+	var res Result
+	res.verbatim = true
+
+	lines := strings.Split(strings.TrimRight(output, "\r\n"), "\n")
+	var perf []string
+	summary, p, found := strings.Cut(lines[0], "|")
+	res.Summary = strings.TrimSpace(summary)
+	if found {
+		perf = append(perf, p)
+	}
+	for i, line := range lines[1:] {
+		text, p, found := strings.Cut(line, "|")
+		if !found {
+			res.LongOutput = append(res.LongOutput, line)
+			continue
+		}
+		if text != "" {
+			res.LongOutput = append(res.LongOutput, text)
+		}
+		// all lines after the separator are performance data
+		perf = append(perf, p)
+		perf = append(perf, lines[i+2:]...)
+		break
+	}
+
+	for _, p := range perf {
+		values, err := perfdata.Parse(p)
+		res.PerfData = append(res.PerfData, values...)
+		if err != nil {
+			res.LongOutput = append(res.LongOutput, fmt.Sprintf("invalid performance data: %v", err))
+		}
+	}
+	return res
+}
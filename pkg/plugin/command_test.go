@@ -0,0 +1,96 @@
+package plugin
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/puffitos/goicinga/pkg/api"
+	"github.com/puffitos/goicinga/pkg/perfdata"
+)
+
+func TestParseOutput(t *testing.T) {
+	tests := []struct {
+		name         string
+		output       string
+		wantSummary  string
+		wantLong     []string
+		wantPerfData []string
+	}{
+		{
+			name:        "summary only",
+			output:      "DISK OK - free space: / 3326 MB (56%)\n",
+			wantSummary: "DISK OK - free space: / 3326 MB (56%)",
+		},
+		{
+			name:         "summary and perfdata",
+			output:       "PING OK - Packet loss = 0%, RTA = 0.80 ms | rta=0.8ms;100;500;0 pl=0%;20;60;0",
+			wantSummary:  "PING OK - Packet loss = 0%, RTA = 0.80 ms",
+			wantPerfData: []string{"rta=0.8ms;100;500;0", "pl=0%;20;60;0"},
+		},
+		{
+			name:         "long output with more perfdata",
+			output:       "DISK OK | /=2643MB;5948;5958;0;5968\n/ 15272 MB (77%);\n/boot 68 MB (69%);\n| /boot=68MB;88;93;0;98\n/home=69357MB;253404;253409;0;253414",
+			wantSummary:  "DISK OK",
+			wantLong:     []string{"/ 15272 MB (77%);", "/boot 68 MB (69%);"},
+			wantPerfData: []string{"/=2643MB;5948;5958;0;5968", "/boot=68MB;88;93;0;98", "/home=69357MB;253404;253409;0;253414"},
+		},
+		{
+			name:         "invalid perfdata",
+			output:       "OK | load1=1 broken",
+			wantSummary:  "OK",
+			wantLong:     []string{`invalid performance data: invalid perfdata "broken": missing '='`},
+			wantPerfData: []string{"load1=1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseOutput(tt.output)
+			if got.Summary != tt.wantSummary {
+				t.Errorf("ParseOutput() summary = %q, want %q", got.Summary, tt.wantSummary)
+			}
+			if !reflect.DeepEqual(got.LongOutput, tt.wantLong) {
+				t.Errorf("ParseOutput() long output = %q, want %q", got.LongOutput, tt.wantLong)
+			}
+			if perf := perfdata.Strings(got.PerfData); !reflect.DeepEqual(perf, tt.wantPerfData) {
+				t.Errorf("ParseOutput() perfdata = %q, want %q", perf, tt.wantPerfData)
+			}
+			if got.Text() != tt.wantSummary && len(tt.wantLong) == 0 {
+				t.Errorf("Text() = %q, want the summary as is", got.Text())
+			}
+		})
+	}
+}
+
+func TestCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		script      string
+		wantState   api.ServiceState
+		wantSummary string
+	}{
+		{name: "ok", script: "echo 'TEST OK | a=1'", wantState: api.ServiceOk, wantSummary: "TEST OK"},
+		{name: "warning", script: "echo 'TEST WARNING'; exit 1", wantState: api.ServiceWarning, wantSummary: "TEST WARNING"},
+		{name: "critical", script: "echo 'TEST CRITICAL'; exit 2", wantState: api.ServiceCritical, wantSummary: "TEST CRITICAL"},
+		{name: "unexpected exit code", script: "echo 'TEST BROKEN'; exit 42", wantState: api.ServiceUnknown, wantSummary: "TEST BROKEN"},
+		{name: "stderr", script: "echo 'no such file' >&2; exit 3", wantState: api.ServiceUnknown, wantSummary: "no such file"},
+		{name: "no output", script: "exit 2", wantState: api.ServiceCritical, wantSummary: "sh exited with code 2 without output"},
+		{name: "timeout", script: "sleep 5", wantState: api.ServiceUnknown, wantSummary: "check timed out after 100ms"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(Command("sh", "-c", tt.script))
+			p.Timeout = 100 * time.Millisecond
+			res := p.Run(context.Background())
+			if res.State != tt.wantState || res.Summary != tt.wantSummary {
+				t.Errorf("Run() = %v %q, want %v %q", res.State, res.Summary, tt.wantState, tt.wantSummary)
+			}
+		})
+	}
+
+	res := New(Command("/nonexistent/check_nothing")).Run(context.Background())
+	if res.State != api.ServiceUnknown {
+		t.Errorf("Run() state = %v, want %v for a missing command", res.State, api.ServiceUnknown)
+	}
+}
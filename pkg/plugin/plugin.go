@@ -0,0 +1,122 @@
+// Package plugin implements the conventions of Icinga check plugins, so checks written in Go
+// can run as local plugins executed by icinga or submit their results as passive checks.
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/puffitos/goicinga/pkg/api"
+)
+
+// DefaultTimeout is the timeout of checks, if a plugin does not set one.
+const DefaultTimeout = 10 * time.Second
+
+// Check performs a check and fills the result. Returning an error yields an UNKNOWN result
+// with the error as summary. The check should return once the context is done.
+type Check func(ctx context.Context, res *Result) error
+
+// Plugin runs a check with the conventions of Icinga check plugins.
+type Plugin struct {
+	// Check is the check to run.
+	Check Check
+	// Timeout bounds the duration of the check. Once it is exceeded, the result is UNKNOWN.
+	// Defaults to 10s.
+	Timeout time.Duration
+}
+
+// New returns a plugin running the given check.
+func New(check Check) *Plugin {
+	return &Plugin{Check: check}
+}
+
+// Run runs the check and returns its result. The result is UNKNOWN if the check returned
+// an error, panicked or did not return before the timeout.
+func (p *Plugin) Run(ctx context.Context) *Result {
+	if p.Check == nil {
+		return unknown("no check to run")
+	}
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// the result is only handed over once the check returned, so a check which
+	// ignores the context cannot modify the returned result
+	done := make(chan *Result, 1)
+	go func() {
+		defer func() {
+			if v := recover(); v != nil {
+				done <- unknown("check panicked: %v", v)
+			}
+		}()
+		res := &Result{}
+		if err := p.Check(ctx, res); err != nil {
+			res.State = api.ServiceUnknown
+			res.Summary = err.Error()
+		}
+		done <- res
+	}()
+
+	select {
+	case res := <-done:
+		if res.State != api.ServiceUnknown || ctx.Err() == nil {
+			return res
+		}
+	case <-ctx.Done():
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return unknown("check timed out after %s", timeout)
+	}
+	return unknown("check canceled: %v", ctx.Err())
+}
+
+// Exec runs the check as local plugin, writing the output to w. Returns the exit code.
+func (p *Plugin) Exec(ctx context.Context, w io.Writer) int {
+	res := p.Run(ctx)
+	if _, err := io.WriteString(w, res.Output()); err != nil {
+		return ExitCode(api.ServiceUnknown)
+	}
+	return ExitCode(res.State)
+}
+
+// Main runs the check as local plugin, writes the output to stdout and exits with the exit code
+// of the state. Flags have to be parsed before.
+func (p *Plugin) Main() {
+	os.Exit(p.Exec(context.Background(), os.Stdout))
+}
+
+// Execute runs the check and returns the check result to submit, with the execution times of the check.
+func (p *Plugin) Execute(ctx context.Context) api.CheckResult {
+	start := time.Now()
+	res := p.Run(ctx)
+	end := time.Now()
+
+	cr := api.CheckResult{
+		ExecutionStart: api.NewIcingaTime(start),
+		ExecutionEnd:   api.NewIcingaTime(end),
+		ExitStatus:     ExitCode(res.State),
+		State:          ExitCode(res.State),
+		Output:         res.Text(),
+	}
+	cr.SetPerfData(res.PerfData...)
+	return cr
+}
+
+// Submit runs the check and submits the result as passive check result of the service on the host.
+// Returns the results of the action, and the error of the submission.
+func (p *Plugin) Submit(ctx context.Context, actions api.Actions, host, service string) (api.ActionResults, error) {
+	if actions == nil {
+		return nil, fmt.Errorf("actions cannot be nil")
+	}
+	srv := &api.Service{HostName: host}
+	srv.Name = service
+	srv.LastCheckResult = p.Execute(ctx)
+	return actions.ProcessCheckResult(ctx, srv)
+}
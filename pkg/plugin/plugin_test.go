@@ -0,0 +1,160 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/puffitos/goicinga/pkg/api"
+	"github.com/puffitos/goicinga/pkg/perfdata"
+)
+
+func TestPlugin_Run(t *testing.T) {
+	tests := []struct {
+		name        string
+		check       Check
+		wantState   api.ServiceState
+		wantSummary string
+	}{
+		{
+			name: "ok",
+			check: func(ctx context.Context, res *Result) error {
+				res.Summarize("all good")
+				return nil
+			},
+			wantState:   api.ServiceOk,
+			wantSummary: "all good",
+		},
+		{
+			name: "critical",
+			check: func(ctx context.Context, res *Result) error {
+				res.Escalate(api.ServiceCritical)
+				res.Summarize("down")
+				return nil
+			},
+			wantState:   api.ServiceCritical,
+			wantSummary: "down",
+		},
+		{
+			name: "error",
+			check: func(ctx context.Context, res *Result) error {
+				res.Escalate(api.ServiceCritical)
+				return errors.New("connection refused")
+			},
+			wantState:   api.ServiceUnknown,
+			wantSummary: "connection refused",
+		},
+		{
+			name: "panic",
+			check: func(ctx context.Context, res *Result) error {
+				panic("boom")
+			},
+			wantState:   api.ServiceUnknown,
+			wantSummary: "check panicked: boom",
+		},
+		{
+			name: "timeout ignoring the context",
+			check: func(ctx context.Context, res *Result) error {
+				time.Sleep(time.Second)
+				res.Summarize("too late")
+				return nil
+			},
+			wantState:   api.ServiceUnknown,
+			wantSummary: "check timed out after 50ms",
+		},
+		{
+			name: "timeout returning the context error",
+			check: func(ctx context.Context, res *Result) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+			wantState:   api.ServiceUnknown,
+			wantSummary: "check timed out after 50ms",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(tt.check)
+			p.Timeout = 50 * time.Millisecond
+			res := p.Run(context.Background())
+			if res.State != tt.wantState || res.Summary != tt.wantSummary {
+				t.Errorf("Run() = %v %q, want %v %q", res.State, res.Summary, tt.wantState, tt.wantSummary)
+			}
+		})
+	}
+}
+
+func TestPlugin_Exec(t *testing.T) {
+	p := New(func(ctx context.Context, res *Result) error {
+		res.Escalate(api.ServiceWarning)
+		res.Summarize("load %.1f", 2.5)
+		res.Details("load5 1.0")
+		res.AddPerfData(perfdata.New("load1", 2.5, ""))
+		return nil
+	})
+	var out bytes.Buffer
+	if code := p.Exec(context.Background(), &out); code != 1 {
+		t.Errorf("Exec() = %d, want 1", code)
+	}
+	if got, want := out.String(), "WARNING - load 2.5 | load1=2.5\nload5 1.0\n"; got != want {
+		t.Errorf("Exec() output = %q, want %q", got, want)
+	}
+}
+
+// recordingActions records the submitted services.
+type recordingActions struct {
+	submitted []*api.Service
+	err       error
+}
+
+func (a *recordingActions) ProcessCheckResult(_ context.Context, srv *api.Service) (api.ActionResults, error) {
+	a.submitted = append(a.submitted, srv)
+	return api.ActionResults{{Code: 200, Name: srv.HostName + "!" + srv.Name}}, a.err
+}
+
+func (a *recordingActions) AcknowledgeProblem(context.Context, *api.AcknowledgeProblemRequest) (api.ActionResults, error) {
+	return nil, nil
+}
+
+func (a *recordingActions) RemoveAcknowledgement(context.Context, *api.RemoveAcknowledgementRequest) (api.ActionResults, error) {
+	return nil, nil
+}
+
+func TestPlugin_Submit(t *testing.T) {
+	p := New(func(ctx context.Context, res *Result) error {
+		res.Escalate(api.ServiceCritical)
+		res.Summarize("disk full")
+		res.Details("/ 100%%")
+		res.AddPerfData(perfdata.New("/", 100, "%"))
+		return nil
+	})
+	actions := &recordingActions{}
+	results, err := p.Submit(context.Background(), actions, "web1", "disk")
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if _, ok := results.ByName("web1!disk"); !ok {
+		t.Errorf("Submit() results = %v", results)
+	}
+	if len(actions.submitted) != 1 {
+		t.Fatalf("Submit() submitted %d results, want 1", len(actions.submitted))
+	}
+	srv := actions.submitted[0]
+	cr := srv.LastCheckResult
+	if srv.HostName != "web1" || srv.Name != "disk" || cr.ExitStatus != 2 {
+		t.Errorf("Submit() submitted %s!%s with exit status %d", srv.HostName, srv.Name, cr.ExitStatus)
+	}
+	if cr.Output != "CRITICAL - disk full\n/ 100%" || strings.Join(cr.PerformanceData, " ") != "/=100%" {
+		t.Errorf("Submit() submitted output %q and perfdata %v", cr.Output, cr.PerformanceData)
+	}
+	if cr.ExecutionStart.IsZero() || cr.ExecutionEnd.Before(cr.ExecutionStart.Time) {
+		t.Errorf("Submit() submitted execution from %v to %v", cr.ExecutionStart, cr.ExecutionEnd)
+	}
+
+	if _, err := p.Submit(context.Background(), nil, "web1", "disk"); err == nil {
+		t.Errorf("Submit() error = nil, want an error without actions")
+	}
+}
@@ -0,0 +1,129 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/puffitos/goicinga/pkg/api"
+	"github.com/puffitos/goicinga/pkg/perfdata"
+)
+
+// stateNames are the names of the service states in the plugin output.
+var stateNames = map[api.ServiceState]string{
+	api.ServiceOk:       "OK",
+	api.ServiceWarning:  "WARNING",
+	api.ServiceCritical: "CRITICAL",
+	api.ServiceUnknown:  "UNKNOWN",
+}
+
+// StateName returns the name of the state, e.g. "WARNING". Unknown states are named "UNKNOWN".
+func StateName(s api.ServiceState) string {
+	if name, ok := stateNames[s]; ok {
+		return name
+	}
+	return stateNames[api.ServiceUnknown]
+}
+
+// ExitCode returns the exit code of a plugin for the state: 0 for OK, 1 for WARNING,
+// 2 for CRITICAL and 3 for UNKNOWN and any other state.
+func ExitCode(s api.ServiceState) int {
+	if _, ok := stateNames[s]; !ok {
+		return int(api.ServiceUnknown)
+	}
+	return int(s)
+}
+
+// severity orders the states by how bad they are: CRITICAL > WARNING > UNKNOWN > OK.
+func severity(s api.ServiceState) int {
+	switch s {
+	case api.ServiceOk:
+		return 0
+	case api.ServiceWarning:
+		return 2
+	case api.ServiceCritical:
+		return 3
+	default:
+		return 1
+	}
+}
+
+// WorstState returns the worst of the states, ordered CRITICAL > WARNING > UNKNOWN > OK.
+func WorstState(states ...api.ServiceState) api.ServiceState {
+	worst := api.ServiceOk
+	for _, s := range states {
+		if severity(s) > severity(worst) {
+			worst = s
+		}
+	}
+	return worst
+}
+
+// Result is the result of a check, which is filled by the check function.
+type Result struct {
+	// State is the state of the checked service.
+	State api.ServiceState
+	// Summary is the first line of the output.
+	Summary string
+	// LongOutput are the additional lines of the output.
+	LongOutput []string
+	// PerfData are the performance data values of the check.
+	PerfData []perfdata.Value
+
+	// verbatim is set for the output of external plugins, whose summary contains the state already
+	verbatim bool
+}
+
+// Escalate sets the state of the result to the given one, if it is worse than the current one.
+func (r *Result) Escalate(s api.ServiceState) {
+	r.State = WorstState(r.State, s)
+}
+
+// Summarize sets the summary of the result.
+func (r *Result) Summarize(format string, args ...interface{}) {
+	r.Summary = fmt.Sprintf(format, args...)
+}
+
+// Details adds a line to the long output of the result.
+func (r *Result) Details(format string, args ...interface{}) {
+	r.LongOutput = append(r.LongOutput, fmt.Sprintf(format, args...))
+}
+
+// AddPerfData adds performance data values to the result.
+func (r *Result) AddPerfData(values ...perfdata.Value) {
+	r.PerfData = append(r.PerfData, values...)
+}
+
+// Text returns the output of the result without performance data, i.e. the state and summary
+// on the first line, followed by the long output. It is submitted as plugin output of passive checks.
+func (r *Result) Text() string {
+	lines := make([]string, 0, len(r.LongOutput)+1)
+	first := StateName(r.State)
+	if r.verbatim {
+		first = r.Summary
+	} else if r.Summary != "" {
+		first += " - " + r.Summary
+	}
+	lines = append(lines, first)
+	lines = append(lines, r.LongOutput...)
+	return strings.Join(lines, "\n")
+}
+
+// Output returns the output of the result in the Nagios plugin format, with the performance
+// data after a '|' on the first line:
+//
+//	STATE - summary | perfdata
+//	long output
+func (r *Result) Output() string {
+	lines := strings.SplitN(r.Text(), "\n", 2)
+	if len(r.PerfData) > 0 {
+		lines[0] += " | " + perfdata.Format(r.PerfData)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// unknown returns an UNKNOWN result with the given summary.
+func unknown(format string, args ...interface{}) *Result {
+	r := &Result{State: api.ServiceUnknown}
+	r.Summarize(format, args...)
+	return r
+}
@@ -0,0 +1,97 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/puffitos/goicinga/pkg/api"
+	"github.com/puffitos/goicinga/pkg/perfdata"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		state api.ServiceState
+		want  int
+		name  string
+	}{
+		{state: api.ServiceOk, want: 0, name: "OK"},
+		{state: api.ServiceWarning, want: 1, name: "WARNING"},
+		{state: api.ServiceCritical, want: 2, name: "CRITICAL"},
+		{state: api.ServiceUnknown, want: 3, name: "UNKNOWN"},
+		{state: api.ServiceState(42), want: 3, name: "UNKNOWN"},
+		{state: api.ServiceState(-1), want: 3, name: "UNKNOWN"},
+	}
+	for _, tt := range tests {
+		if got := ExitCode(tt.state); got != tt.want {
+			t.Errorf("ExitCode(%d) = %d, want %d", tt.state, got, tt.want)
+		}
+		if got := StateName(tt.state); got != tt.name {
+			t.Errorf("StateName(%d) = %q, want %q", tt.state, got, tt.name)
+		}
+	}
+}
+
+func TestWorstState(t *testing.T) {
+	tests := []struct {
+		name   string
+		states []api.ServiceState
+		want   api.ServiceState
+	}{
+		{name: "none", want: api.ServiceOk},
+		{name: "unknown over ok", states: []api.ServiceState{api.ServiceOk, api.ServiceUnknown}, want: api.ServiceUnknown},
+		{name: "warning over unknown", states: []api.ServiceState{api.ServiceUnknown, api.ServiceWarning}, want: api.ServiceWarning},
+		{name: "critical over all", states: []api.ServiceState{api.ServiceCritical, api.ServiceWarning, api.ServiceUnknown}, want: api.ServiceCritical},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WorstState(tt.states...); got != tt.want {
+				t.Errorf("WorstState() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResult_Output(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   Result
+		wantText string
+		want     string
+	}{
+		{
+			name:     "state only",
+			result:   Result{},
+			wantText: "OK",
+			want:     "OK\n",
+		},
+		{
+			name: "long output and perfdata",
+			result: Result{
+				State:      api.ServiceWarning,
+				Summary:    "disk usage 85%",
+				LongOutput: []string{"/ 85%", "/var 40%"},
+				PerfData:   []perfdata.Value{perfdata.New("/", 85, "%").WithWarn(perfdata.NewRange(0, 80))},
+			},
+			wantText: "WARNING - disk usage 85%\n/ 85%\n/var 40%",
+			want:     "WARNING - disk usage 85% | /=85%;80\n/ 85%\n/var 40%\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.result.Text(); got != tt.wantText {
+				t.Errorf("Text() = %q, want %q", got, tt.wantText)
+			}
+			if got := tt.result.Output(); got != tt.want {
+				t.Errorf("Output() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResult_Escalate(t *testing.T) {
+	var r Result
+	r.Escalate(api.ServiceCritical)
+	r.Escalate(api.ServiceWarning)
+	if r.State != api.ServiceCritical {
+		t.Errorf("Escalate() state = %v, want %v", r.State, api.ServiceCritical)
+	}
+}
@@ -0,0 +1,69 @@
+package plugin
+
+import (
+	"flag"
+
+	"github.com/puffitos/goicinga/pkg/api"
+	"github.com/puffitos/goicinga/pkg/perfdata"
+)
+
+// Thresholds are the warning and critical ranges of a check.
+type Thresholds struct {
+	// Warn is the warning range, nil if there is none.
+	Warn *perfdata.Range
+	// Crit is the critical range, nil if there is none.
+	Crit *perfdata.Range
+}
+
+// RegisterFlags registers the thresholds as the flags -warning and -critical, with the
+// shorthands -w and -c, on the flag set. The flags accept ranges in the Nagios plugin format.
+func (t *Thresholds) RegisterFlags(fs *flag.FlagSet) {
+	t.RegisterPrefixedFlags(fs, "")
+}
+
+// RegisterPrefixedFlags registers the thresholds as the flags -<prefix>warning and -<prefix>critical
+// on the flag set, e.g. for checks with several thresholds. Without prefix, the shorthands
+// -w and -c are registered as well.
+func (t *Thresholds) RegisterPrefixedFlags(fs *flag.FlagSet, prefix string) {
+	warn, crit := &rangeFlag{r: &t.Warn}, &rangeFlag{r: &t.Crit}
+	fs.Var(warn, prefix+"warning", "warning threshold range, e.g. 10, 10:, ~:10, 10:20 or @10:20")
+	fs.Var(crit, prefix+"critical", "critical threshold range, e.g. 10, 10:, ~:10, 10:20 or @10:20")
+	if prefix == "" {
+		fs.Var(warn, "w", "shorthand for -warning")
+		fs.Var(crit, "c", "shorthand for -critical")
+	}
+}
+
+// State returns the state for the value: CRITICAL if it alerts on the critical range,
+// WARNING if it alerts on the warning range and OK otherwise.
+func (t *Thresholds) State(value float64) api.ServiceState {
+	return api.ServiceStateFor(value, t.Warn, t.Crit)
+}
+
+// PerfData returns the performance data value with the thresholds.
+func (t *Thresholds) PerfData(label string, value float64, uom string) perfdata.Value {
+	v := perfdata.New(label, value, uom)
+	v.Warn, v.Crit = t.Warn, t.Crit
+	return v
+}
+
+// rangeFlag is a flag.Value setting a range.
+type rangeFlag struct {
+	r **perfdata.Range
+}
+
+func (f *rangeFlag) String() string {
+	if f.r == nil || *f.r == nil {
+		return ""
+	}
+	return (*f.r).String()
+}
+
+func (f *rangeFlag) Set(s string) error {
+	r, err := perfdata.ParseRange(s)
+	if err != nil {
+		return err
+	}
+	*f.r = &r
+	return nil
+}
@@ -0,0 +1,59 @@
+package plugin
+
+import (
+	"flag"
+	"io"
+	"testing"
+
+	"github.com/puffitos/goicinga/pkg/api"
+)
+
+func TestThresholds_RegisterFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		value   float64
+		want    api.ServiceState
+		wantErr bool
+	}{
+		{name: "no thresholds", value: 100, want: api.ServiceOk},
+		{name: "ok", args: []string{"-warning", "80", "-critical", "90"}, value: 50, want: api.ServiceOk},
+		{name: "warning", args: []string{"-w", "80", "-c", "90"}, value: 85, want: api.ServiceWarning},
+		{name: "critical", args: []string{"-w", "80", "-c", "90"}, value: 95, want: api.ServiceCritical},
+		{name: "inverted", args: []string{"-c", "@0:10"}, value: 5, want: api.ServiceCritical},
+		{name: "invalid range", args: []string{"-w", "20:10"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			var th Thresholds
+			th.RegisterFlags(fs)
+			err := fs.Parse(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := th.State(tt.value); got != tt.want {
+				t.Errorf("State() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThresholds_PerfData(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	var load Thresholds
+	load.RegisterPrefixedFlags(fs, "load-")
+	if err := fs.Parse([]string{"-load-warning", "2", "-load-critical", "4:"}); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got, want := load.PerfData("load1", 1.5, "").String(), "load1=1.5;2;4:"; got != want {
+		t.Errorf("PerfData() = %q, want %q", got, want)
+	}
+	if fs.Lookup("w") != nil {
+		t.Errorf("shorthand registered for prefixed thresholds")
+	}
+}
@@ -0,0 +1,250 @@
+// Package runner schedules checks on intervals and submits their results as passive check results,
+// so a single daemon can monitor services icinga cannot check actively.
+package runner
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/puffitos/goicinga/pkg/api"
+	"github.com/puffitos/goicinga/pkg/plugin"
+)
+
+const defaultInterval = time.Minute
+
+// Submitter submits check results, e.g. api.Actions or a spool.Submitter.
+type Submitter interface {
+	ProcessCheckResult(ctx context.Context, srv *api.Service) (api.ActionResults, error)
+}
+
+// Job is a check scheduled by the runner.
+type Job struct {
+	// Host is the name of the host the service belongs to.
+	Host string
+	// Service is the name of the service the results are submitted for.
+	Service string
+	// Check is the check to run, e.g. a Go function or plugin.Command for external plugins.
+	Check plugin.Check
+	// Interval is the interval the check is run in. Defaults to 1m.
+	Interval time.Duration
+	// Timeout bounds the duration of the check, it is UNKNOWN once it is exceeded. Defaults to 10s.
+	Timeout time.Duration
+	// TTL is the time icinga waits for the next result, before its freshness check is executed.
+	// Defaults to twice the interval plus the jitter and timeout, i.e. a single missed result is tolerated.
+	TTL time.Duration
+}
+
+// Config configures a Runner.
+type Config struct {
+	// Jitter is the maximum random delay added to every interval, to spread the checks over time.
+	// The first run of every job is delayed randomly by up to the jitter as well. Zero disables it.
+	Jitter time.Duration
+	// CheckSource is submitted as the node which executed the checks. Defaults to the hostname.
+	CheckSource string
+	// Template is the service template missing services are created from, before their result is
+	// submitted again. Empty disables the creation of services.
+	Template string
+	// Services creates the missing services. Required if Template is set.
+	Services api.Services
+	// OnResult is called with the result of every run, and the error of its submission.
+	// It is called concurrently for different jobs.
+	OnResult func(job *Job, cr *api.CheckResult, err error)
+}
+
+// Runner runs scheduled checks and submits their results.
+type Runner struct {
+	submitter Submitter
+	services  api.Services
+	template  string
+	jitter    time.Duration
+	source    string
+	onResult  func(*Job, *api.CheckResult, error)
+	log       logr.Logger
+
+	// random returns a random duration in [0, n), it is replaced in tests
+	random func(n time.Duration) time.Duration
+
+	mu      sync.Mutex
+	jobs    []*Job
+	running bool
+}
+
+// New returns a Runner submitting the results through the given submitter.
+func New(submitter Submitter, config *Config, log *logr.Logger) (*Runner, error) {
+	if log == nil {
+		l := logr.Discard()
+		log = &l
+	}
+	if submitter == nil {
+		return nil, fmt.Errorf("submitter cannot be nil")
+	}
+	if config.Template != "" && config.Services == nil {
+		return nil, fmt.Errorf("services are required to create services from template %s", config.Template)
+	}
+	if config.Jitter < 0 {
+		return nil, fmt.Errorf("jitter cannot be negative")
+	}
+
+	source := config.CheckSource
+	if source == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine check source: %w", err)
+		}
+		source = hostname
+	}
+	return &Runner{
+		submitter: submitter,
+		services:  config.Services,
+		template:  config.Template,
+		jitter:    config.Jitter,
+		source:    source,
+		onResult:  config.OnResult,
+		log:       log.WithName("runner"),
+		random: func(n time.Duration) time.Duration {
+			return time.Duration(rand.Int63n(int64(n)))
+		},
+	}, nil
+}
+
+// Add schedules the job. Jobs have to be added before Run is called.
+func (r *Runner) Add(job Job) error {
+	if job.Host == "" || job.Service == "" {
+		return fmt.Errorf("host and service are required")
+	}
+	if job.Check == nil {
+		return fmt.Errorf("check of %s!%s cannot be nil", job.Host, job.Service)
+	}
+	if job.Interval < 0 || job.Timeout < 0 || job.TTL < 0 {
+		return fmt.Errorf("interval, timeout and ttl of %s!%s cannot be negative", job.Host, job.Service)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.running {
+		return fmt.Errorf("runner is already running")
+	}
+	r.jobs = append(r.jobs, &job)
+	return nil
+}
+
+// Run runs the jobs on their intervals until the context is canceled. Runs in progress are
+// finished, before Run returns the error of the context.
+func (r *Runner) Run(ctx context.Context) error {
+	r.mu.Lock()
+	if r.running {
+		r.mu.Unlock()
+		return fmt.Errorf("runner is already running")
+	}
+	r.running = true
+	jobs := r.jobs
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		r.running = false
+		r.mu.Unlock()
+	}()
+
+	r.log.Info("starting check runner", "jobs", len(jobs), "source", r.source)
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		wg.Add(1)
+		go func(job *Job) {
+			defer wg.Done()
+			r.schedule(ctx, job)
+		}(job)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// schedule runs the job on its interval until the context is canceled.
+func (r *Runner) schedule(ctx context.Context, job *Job) {
+	timer := time.NewTimer(r.delay(0))
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		r.run(ctx, job)
+		timer.Reset(r.delay(intervalOf(job)))
+	}
+}
+
+// delay returns the interval plus a random jitter.
+func (r *Runner) delay(interval time.Duration) time.Duration {
+	if r.jitter <= 0 {
+		return interval
+	}
+	return interval + r.random(r.jitter)
+}
+
+// run runs the check of the job once and submits its result. If the service does not exist and
+// a template is configured, the service is created and the result is submitted again.
+func (r *Runner) run(ctx context.Context, job *Job) {
+	p := plugin.New(job.Check)
+	p.Timeout = job.Timeout
+	cr := p.Execute(ctx)
+	cr.CheckSource = r.source
+	cr.TTL = api.IcingaDuration(r.ttl(job))
+
+	srv := &api.Service{HostName: job.Host}
+	srv.Name = job.Service
+	srv.LastCheckResult = cr
+
+	_, err := r.submitter.ProcessCheckResult(ctx, srv)
+	if api.IsNotFound(err) && r.template != "" {
+		if err = r.create(ctx, job); err == nil {
+			_, err = r.submitter.ProcessCheckResult(ctx, srv)
+		}
+	}
+	if err != nil {
+		r.log.Error(err, "failed to submit check result", "host", job.Host, "service", job.Service)
+	} else {
+		r.log.V(1).Info("submitted check result", "host", job.Host, "service", job.Service, "exit_status", cr.ExitStatus)
+	}
+	if r.onResult != nil {
+		r.onResult(job, &cr, err)
+	}
+}
+
+// create creates the service of the job from the template. A service created concurrently is not an error.
+func (r *Runner) create(ctx context.Context, job *Job) error {
+	svc := &api.Service{HostName: job.Host}
+	svc.Name = job.Host + "!" + job.Service
+	svc.Templates = []string{r.template}
+
+	r.log.Info("creating missing service", "host", job.Host, "service", job.Service, "template", r.template)
+	err := r.services.Create(ctx, svc)
+	if err != nil && !api.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create service %s: %w", svc.Name, err)
+	}
+	return nil
+}
+
+// ttl returns the TTL of the results of the job.
+func (r *Runner) ttl(job *Job) time.Duration {
+	if job.TTL > 0 {
+		return job.TTL
+	}
+	timeout := job.Timeout
+	if timeout <= 0 {
+		timeout = plugin.DefaultTimeout
+	}
+	return 2*intervalOf(job) + r.jitter + timeout
+}
+
+func intervalOf(job *Job) time.Duration {
+	if job.Interval <= 0 {
+		return defaultInterval
+	}
+	return job.Interval
+}
@@ -0,0 +1,234 @@
+package runner
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/puffitos/goicinga/pkg/api"
+	"github.com/puffitos/goicinga/pkg/plugin"
+)
+
+// fakeSubmitter records the submitted services. Submissions fail with not found
+// until the service was created through fakeServices.
+type fakeSubmitter struct {
+	mu        sync.Mutex
+	submitted []*api.Service
+	services  *fakeServices
+}
+
+func (s *fakeSubmitter) ProcessCheckResult(_ context.Context, srv *api.Service) (api.ActionResults, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.services != nil && !s.services.exists(srv.HostName+"!"+srv.Name) {
+		return nil, &api.IcingaError{Err: http.StatusNotFound, Status: "No objects found."}
+	}
+	s.submitted = append(s.submitted, srv)
+	return api.ActionResults{{Code: 200, Name: srv.HostName + "!" + srv.Name}}, nil
+}
+
+func (s *fakeSubmitter) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.submitted)
+}
+
+// fakeServices records the created services, other methods are not implemented.
+type fakeServices struct {
+	api.Services
+
+	mu      sync.Mutex
+	created []*api.Service
+	err     error
+}
+
+func (s *fakeServices) Create(_ context.Context, svc *api.Service) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.created = append(s.created, svc)
+	return nil
+}
+
+func (s *fakeServices) exists(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, svc := range s.created {
+		if svc.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func critical(ctx context.Context, res *plugin.Result) error {
+	res.Escalate(api.ServiceCritical)
+	res.Summarize("down")
+	return nil
+}
+
+func TestRunner_run(t *testing.T) {
+	sub := &fakeSubmitter{}
+	var gotErr error
+	r, err := New(sub, &Config{
+		CheckSource: "runner1",
+		Jitter:      5 * time.Second,
+		OnResult:    func(job *Job, cr *api.CheckResult, err error) { gotErr = err },
+	}, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	job := &Job{Host: "web1", Service: "http", Check: critical, Interval: time.Minute, Timeout: 20 * time.Second}
+	before := time.Now()
+	r.run(context.Background(), job)
+	if gotErr != nil || len(sub.submitted) != 1 {
+		t.Fatalf("run() submitted %d results, error = %v", len(sub.submitted), gotErr)
+	}
+
+	srv := sub.submitted[0]
+	cr := srv.LastCheckResult
+	if srv.HostName != "web1" || srv.Name != "http" {
+		t.Errorf("run() submitted for %s!%s", srv.HostName, srv.Name)
+	}
+	if cr.ExitStatus != 2 || cr.Output != "CRITICAL - down" {
+		t.Errorf("run() submitted exit status %d and output %q", cr.ExitStatus, cr.Output)
+	}
+	if cr.CheckSource != "runner1" {
+		t.Errorf("run() submitted check source %q, want runner1", cr.CheckSource)
+	}
+	if want := 2*time.Minute + 5*time.Second + 20*time.Second; cr.TTL.Duration() != want {
+		t.Errorf("run() submitted ttl %v, want %v", cr.TTL, want)
+	}
+	if cr.ExecutionStart.Before(before) || cr.ExecutionEnd.Before(cr.ExecutionStart.Time) {
+		t.Errorf("run() submitted execution from %v to %v", cr.ExecutionStart, cr.ExecutionEnd)
+	}
+
+	job.TTL = time.Hour
+	r.run(context.Background(), job)
+	if got := sub.submitted[1].LastCheckResult.TTL.Duration(); got != time.Hour {
+		t.Errorf("run() submitted ttl %v, want the ttl of the job", got)
+	}
+}
+
+func TestRunner_run_createService(t *testing.T) {
+	tests := []struct {
+		name        string
+		template    string
+		createErr   error
+		wantCreated int
+		wantErr     bool
+	}{
+		{name: "created from template", template: "passive-service", wantCreated: 1},
+		{name: "no template", wantErr: true},
+		{
+			name:      "creation fails",
+			template:  "passive-service",
+			createErr: &api.IcingaError{Err: http.StatusInternalServerError, Status: "Object could not be created."},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			services := &fakeServices{err: tt.createErr}
+			sub := &fakeSubmitter{services: services}
+			var gotErr error
+			r, err := New(sub, &Config{
+				CheckSource: "runner1",
+				Template:    tt.template,
+				Services:    services,
+				OnResult:    func(job *Job, cr *api.CheckResult, err error) { gotErr = err },
+			}, nil)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			r.run(context.Background(), &Job{Host: "web1", Service: "http", Check: critical})
+			if (gotErr != nil) != tt.wantErr {
+				t.Fatalf("run() error = %v, wantErr %v", gotErr, tt.wantErr)
+			}
+			if len(services.created) != tt.wantCreated {
+				t.Fatalf("run() created %d services, want %d", len(services.created), tt.wantCreated)
+			}
+			if tt.wantCreated > 0 {
+				svc := services.created[0]
+				if svc.Name != "web1!http" || len(svc.Templates) != 1 || svc.Templates[0] != tt.template {
+					t.Errorf("run() created %s from %v", svc.Name, svc.Templates)
+				}
+				if len(sub.submitted) != 1 {
+					t.Errorf("run() submitted %d results after creating the service, want 1", len(sub.submitted))
+				}
+			}
+		})
+	}
+}
+
+func TestRunner_Run(t *testing.T) {
+	sub := &fakeSubmitter{}
+	r, err := New(sub, &Config{CheckSource: "runner1", Jitter: time.Second}, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	var mu sync.Mutex
+	var delays []time.Duration
+	r.random = func(n time.Duration) time.Duration {
+		mu.Lock()
+		defer mu.Unlock()
+		delays = append(delays, n)
+		return time.Millisecond
+	}
+	for _, svc := range []string{"a", "b"} {
+		if err := r.Add(Job{Host: "web1", Service: svc, Check: critical, Interval: 10 * time.Millisecond}); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- r.Run(ctx) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for sub.count() < 6 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := r.Add(Job{Host: "web1", Service: "c", Check: critical}); err == nil {
+		t.Errorf("Add() error = nil, want an error while running")
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Run() error = %v, want %v", err, context.Canceled)
+	}
+	if n := sub.count(); n < 6 {
+		t.Errorf("Run() submitted %d results, want at least 6", n)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, d := range delays {
+		if d != time.Second {
+			t.Errorf("Run() requested jitter up to %v, want %v", d, time.Second)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(nil, &Config{}, nil); err == nil {
+		t.Errorf("New() error = nil, want an error without submitter")
+	}
+	if _, err := New(&fakeSubmitter{}, &Config{Template: "passive-service"}, nil); err == nil {
+		t.Errorf("New() error = nil, want an error for a template without services")
+	}
+	r, err := New(&fakeSubmitter{}, &Config{}, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if r.source == "" {
+		t.Errorf("New() check source is empty, want the hostname")
+	}
+	if err := r.Add(Job{Host: "web1", Service: "http"}); err == nil {
+		t.Errorf("Add() error = nil, want an error without check")
+	}
+}
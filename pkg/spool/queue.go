@@ -0,0 +1,356 @@
+package spool
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/puffitos/goicinga/pkg/api"
+)
+
+const (
+	logFilePattern = "spool-%d.log"
+	offsetFileName = "spool.offset"
+)
+
+// errEntryTooLarge is returned if a single entry exceeds the maximum size of the spool.
+var errEntryTooLarge = errors.New("check result exceeds the maximum spool size")
+
+// Entry is a passive check result held in the spool.
+type Entry struct {
+	// Host is the name of the host of the service.
+	Host string `json:"host"`
+	// Service is the name of the service.
+	Service string `json:"service"`
+	// Result is the check result to submit.
+	Result api.CheckResult `json:"result"`
+	// QueuedAt is the time the result was written to the spool.
+	QueuedAt time.Time `json:"queued_at"`
+}
+
+// queue is an append-only file of JSON encoded entries, one per line.
+// The offset of the first entry which was not yet submitted is kept in a separate file,
+// so the queue survives restarts. Entries are delivered at least once.
+// Compacting the queue writes a new log file of the next generation, which is committed
+// by persisting its generation together with the offset.
+// The queue is not safe for concurrent use.
+type queue struct {
+	dir      string
+	maxBytes int64
+	ttl      time.Duration
+
+	f *os.File
+	// gen is the generation of the log file
+	gen uint64
+	// offset is the position of the first pending entry in the log file
+	offset int64
+	// size is the size of the log file
+	size  int64
+	depth int
+
+	// held is set while the first pending entry is replayed, it is kept by compact
+	// until it is acknowledged or released
+	held bool
+	// heldOffset and heldNext are the positions of the held entry and the entry after it
+	heldOffset, heldNext int64
+
+	dropped uint64
+	expired uint64
+}
+
+// openQueue opens the queue in the given directory, creating it if necessary.
+func openQueue(dir string, maxBytes int64, ttl time.Duration) (*queue, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+	q := &queue{dir: dir, maxBytes: maxBytes, ttl: ttl}
+	if err := q.open(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// open restores the generation and offset, opens the log file and counts the pending entries.
+// An incomplete last entry, e.g. after a crash, is removed, as are log files of other generations.
+func (q *queue) open() error {
+	b, err := os.ReadFile(filepath.Join(q.dir, offsetFileName))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		q.gen, q.offset = 0, 0
+	case err != nil:
+		return fmt.Errorf("failed to read spool offset: %w", err)
+	default:
+		if _, err := fmt.Sscanf(string(b), "%d %d", &q.gen, &q.offset); err != nil {
+			return fmt.Errorf("invalid spool offset: %w", err)
+		}
+	}
+	if err := q.removeStale(); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(q.logPath(q.gen), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open spool: %w", err)
+	}
+	q.f = f
+
+	fi, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat spool: %w", err)
+	}
+	q.size = fi.Size()
+	if q.offset > q.size {
+		q.offset = q.size
+	}
+
+	// count the complete entries and cut off a partially written one
+	q.depth = 0
+	end := q.offset
+	r := bufio.NewReader(io.NewSectionReader(f, q.offset, q.size-q.offset))
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			break
+		}
+		end += int64(len(line))
+		q.depth++
+	}
+	if end != q.size {
+		if err := f.Truncate(end); err != nil {
+			return fmt.Errorf("failed to truncate spool: %w", err)
+		}
+		q.size = end
+	}
+	return nil
+}
+
+// push appends the entry to the queue. If the queue would exceed its maximum size,
+// expired entries and, if necessary, the oldest entries are dropped first.
+func (q *queue) push(e *Entry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if q.maxBytes > 0 && int64(len(b)) > q.maxBytes {
+		return errEntryTooLarge
+	}
+
+	if q.maxBytes > 0 && q.size+int64(len(b)) > q.maxBytes {
+		if err := q.compact(q.maxBytes - int64(len(b))); err != nil {
+			return err
+		}
+	}
+
+	if _, err := q.f.WriteAt(b, q.size); err != nil {
+		return fmt.Errorf("failed to write to spool: %w", err)
+	}
+	if err := q.f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync spool: %w", err)
+	}
+	q.size += int64(len(b))
+	q.depth++
+	return nil
+}
+
+// peek returns the first pending entry and holds it, until it is acknowledged or released.
+// Expired entries are dropped. Returns nil if the queue is empty.
+func (q *queue) peek() (*Entry, error) {
+	q.held = false
+	for q.depth > 0 {
+		line, err := bufio.NewReader(io.NewSectionReader(q.f, q.offset, q.size-q.offset)).ReadBytes('\n')
+		if err != nil {
+			return nil, fmt.Errorf("failed to read spool: %w", err)
+		}
+		next := q.offset + int64(len(line))
+
+		var e Entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			// a corrupt entry can never be submitted, so it is skipped
+			q.dropped++
+			if err := q.remove(next); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if q.isExpired(&e) {
+			q.expired++
+			if err := q.remove(next); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		q.held, q.heldOffset, q.heldNext = true, q.offset, next
+		return &e, nil
+	}
+	return nil, nil
+}
+
+// ack removes the held entry, once it was submitted.
+func (q *queue) ack() error {
+	if !q.held || q.heldOffset != q.offset {
+		return errors.New("replayed check result is no longer the first in the spool")
+	}
+	q.held = false
+	return q.remove(q.heldNext)
+}
+
+// release keeps the held entry in the queue, e.g. if it could not be submitted.
+func (q *queue) release() {
+	q.held = false
+}
+
+// remove removes the first pending entry, by moving the offset to the given position.
+// Once all entries are removed, the log file is truncated.
+func (q *queue) remove(next int64) error {
+	q.offset = next
+	q.depth--
+	if q.depth == 0 {
+		if err := q.f.Truncate(0); err != nil {
+			return fmt.Errorf("failed to truncate spool: %w", err)
+		}
+		q.offset, q.size = 0, 0
+	}
+	return q.writeOffset()
+}
+
+// compact rewrites the pending entries to a new log file, dropping expired entries and
+// as many of the oldest entries as needed to fit into the given size. A held entry is kept,
+// so the queue may exceed the size by it until the entry is acknowledged.
+func (q *queue) compact(limit int64) error {
+	// the held entry is the first pending one, it is kept as is
+	keep := 0
+	if q.held && q.heldOffset == q.offset {
+		keep = 1
+	}
+
+	var entries [][]byte
+	var total int64
+	r := bufio.NewReader(io.NewSectionReader(q.f, q.offset, q.size-q.offset))
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			break
+		}
+		if len(entries) < keep {
+			entries = append(entries, line)
+			total += int64(len(line))
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			q.dropped++
+			continue
+		}
+		if q.isExpired(&e) {
+			q.expired++
+			continue
+		}
+		entries = append(entries, line)
+		total += int64(len(line))
+	}
+	q.held = keep == 1 && len(entries) > 0
+	for len(entries) > keep && total > limit {
+		total -= int64(len(entries[keep]))
+		entries = append(entries[:keep], entries[keep+1:]...)
+		q.dropped++
+	}
+
+	// the new log file is committed by persisting its generation with the offset,
+	// a crash before leaves the previous log file and offset in place
+	oldGen, oldOffset := q.gen, q.offset
+	if err := writeFileSync(q.logPath(oldGen+1), bytes.Join(entries, nil)); err != nil {
+		return fmt.Errorf("failed to compact spool: %w", err)
+	}
+	q.gen, q.offset = oldGen+1, 0
+	if err := q.writeOffset(); err != nil {
+		q.gen, q.offset = oldGen, oldOffset
+		_ = os.Remove(q.logPath(oldGen + 1))
+		return err
+	}
+	if err := q.f.Close(); err != nil {
+		return fmt.Errorf("failed to close spool: %w", err)
+	}
+	if q.held {
+		q.heldOffset, q.heldNext = 0, int64(len(entries[0]))
+	}
+	return q.open()
+}
+
+// writeOffset persists the generation and offset atomically.
+func (q *queue) writeOffset() error {
+	tmp := filepath.Join(q.dir, offsetFileName+".tmp")
+	if err := writeFileSync(tmp, []byte(fmt.Sprintf("%d %d", q.gen, q.offset))); err != nil {
+		return fmt.Errorf("failed to write spool offset: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(q.dir, offsetFileName)); err != nil {
+		return fmt.Errorf("failed to write spool offset: %w", err)
+	}
+	return nil
+}
+
+// logPath returns the path of the log file of the given generation.
+func (q *queue) logPath(gen uint64) string {
+	return filepath.Join(q.dir, fmt.Sprintf(logFilePattern, gen))
+}
+
+// removeStale removes the log files of other generations, which are left behind
+// by a crash during or right after a compaction.
+func (q *queue) removeStale() error {
+	paths, err := filepath.Glob(filepath.Join(q.dir, "spool-*.log"))
+	if err != nil {
+		return fmt.Errorf("failed to list spool files: %w", err)
+	}
+	for _, path := range paths {
+		if path == q.logPath(q.gen) {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove stale spool file: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeFileSync writes the data to the file and syncs it to disk.
+func writeFileSync(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// isExpired reports whether the entry is older than the TTL of the spool, or than the TTL of its
+// result. Once the TTL of the result passed, icinga ran the freshness check of the service,
+// whose state must not be overwritten by the stale result.
+func (q *queue) isExpired(e *Entry) bool {
+	age := time.Since(e.QueuedAt)
+	if q.ttl > 0 && age > q.ttl {
+		return true
+	}
+	return e.Result.TTL > 0 && age > e.Result.TTL.Duration()
+}
+
+// bytes returns the size of the pending entries.
+func (q *queue) bytes() int64 {
+	return q.size - q.offset
+}
+
+func (q *queue) close() error {
+	return q.f.Close()
+}
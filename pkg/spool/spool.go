@@ -0,0 +1,283 @@
+// Package spool keeps passive check results on disk while the icinga API is unavailable
+// and submits them once it is reachable again.
+package spool
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/puffitos/goicinga/pkg/api"
+)
+
+const (
+	defaultMaxBytes      = 64 << 20
+	defaultRetryInterval = 10 * time.Second
+)
+
+// Config configures a spooling Submitter.
+type Config struct {
+	// Dir is the directory the spool is stored in. It is created if it does not exist.
+	Dir string
+	// MaxBytes bounds the size of the spool on disk. Once it is reached, expired
+	// and then the oldest results are dropped, except for the one being replayed. Defaults to 64MiB.
+	MaxBytes int64
+	// TTL is the maximum age of a spooled result. Older results are dropped instead
+	// of being submitted. Results with a TTL of their own are dropped once it passed as well.
+	// Zero keeps results without a TTL until they are submitted.
+	TTL time.Duration
+	// RetryInterval is the interval Run tries to submit the spooled results in. Defaults to 10s.
+	RetryInterval time.Duration
+	// OnDepthChange is called with the number of spooled results whenever it changes.
+	OnDepthChange func(depth int)
+}
+
+// Stats are the metrics of a spool.
+type Stats struct {
+	// Depth is the number of spooled results.
+	Depth int
+	// Bytes is the size of the spooled results on disk.
+	Bytes int64
+	// Dropped is the number of results dropped to stay within MaxBytes, or because they were unreadable.
+	Dropped uint64
+	// Expired is the number of results dropped because their TTL passed.
+	Expired uint64
+	// Rejected is the number of spooled results dropped because the icinga API refused them.
+	Rejected uint64
+	// Replayed is the number of spooled results submitted successfully.
+	Replayed uint64
+}
+
+// Submitter submits passive check results through api.Actions and spools them on disk
+// if the icinga API is unavailable. Spooled results are replayed in the order they were
+// submitted, either by Run in the background or by calling Flush.
+// While results are spooled, new results are spooled as well to keep them in order.
+type Submitter struct {
+	actions  api.Actions
+	interval time.Duration
+	onDepth  func(int)
+	log      logr.Logger
+
+	// mu guards the queue and the stats
+	mu       sync.Mutex
+	q        *queue
+	rejected uint64
+	replayed uint64
+	// flushMu serializes replays, without blocking new results while waiting for the API
+	flushMu sync.Mutex
+}
+
+// NewSubmitter opens the spool in the configured directory and returns a Submitter
+// which submits through the given actions. Results spooled by a previous process are kept.
+func NewSubmitter(actions api.Actions, config *Config, log *logr.Logger) (*Submitter, error) {
+	if log == nil {
+		l := logr.Discard()
+		log = &l
+	}
+	if actions == nil {
+		return nil, fmt.Errorf("actions cannot be nil")
+	}
+	if config.Dir == "" {
+		return nil, fmt.Errorf("spool directory cannot be empty")
+	}
+
+	maxBytes := config.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxBytes
+	}
+	q, err := openQueue(config.Dir, maxBytes, config.TTL)
+	if err != nil {
+		return nil, err
+	}
+
+	interval := config.RetryInterval
+	if interval <= 0 {
+		interval = defaultRetryInterval
+	}
+	s := &Submitter{
+		actions:  actions,
+		interval: interval,
+		onDepth:  config.OnDepthChange,
+		log:      log.WithName("spool"),
+		q:        q,
+	}
+	if q.depth > 0 {
+		s.log.Info("found spooled check results", "depth", q.depth)
+	}
+	return s, nil
+}
+
+// ProcessCheckResult submits the check result of the service. If the icinga API is
+// unavailable, the result is spooled and no results and no error are returned.
+// Errors for the result itself, e.g. an unknown service, are returned as is.
+func (s *Submitter) ProcessCheckResult(ctx context.Context, srv *api.Service) (api.ActionResults, error) {
+	if srv == nil {
+		return nil, fmt.Errorf("service cannot be nil")
+	}
+
+	s.mu.Lock()
+	pending := s.q.depth > 0
+	s.mu.Unlock()
+
+	if !pending {
+		results, err := s.actions.ProcessCheckResult(ctx, srv)
+		if err == nil || !isUnavailable(err) {
+			return results, err
+		}
+		s.log.V(1).Info("icinga API unavailable, spooling check result", "host", srv.HostName, "service", srv.Name, "error", err.Error())
+	}
+
+	return nil, s.push(&Entry{
+		Host:     srv.HostName,
+		Service:  srv.Name,
+		Result:   srv.LastCheckResult,
+		QueuedAt: time.Now(),
+	})
+}
+
+// Flush submits the spooled results in order, until the spool is empty or the icinga API
+// is unavailable. Results the icinga API refuses are dropped. Other errors, e.g. of the
+// credential provider, stop the flush and keep the result.
+func (s *Submitter) Flush(ctx context.Context) error {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	for {
+		s.mu.Lock()
+		before := s.q.depth
+		e, err := s.q.peek()
+		after := s.q.depth
+		s.mu.Unlock()
+		if before != after {
+			s.notify(after)
+		}
+		if err != nil || e == nil {
+			return err
+		}
+
+		// the result is held while it is submitted, so a concurrent compaction keeps it
+		_, err = s.actions.ProcessCheckResult(ctx, e.service())
+		if err != nil && (isUnavailable(err) || api.StatusCode(err) == 0) {
+			s.mu.Lock()
+			s.q.release()
+			s.mu.Unlock()
+			return err
+		}
+
+		s.mu.Lock()
+		if err != nil {
+			s.rejected++
+			s.log.Error(err, "dropping spooled check result refused by icinga", "host", e.Host, "service", e.Service)
+		} else {
+			s.replayed++
+		}
+		err = s.q.ack()
+		depth := s.q.depth
+		s.mu.Unlock()
+		s.notify(depth)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Run flushes the spool every RetryInterval, until the context is canceled.
+func (s *Submitter) Run(ctx context.Context) error {
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+	for {
+		if err := s.Flush(ctx); err != nil && ctx.Err() == nil {
+			s.log.V(1).Info("failed to flush spool", "error", err.Error())
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// Depth returns the number of spooled results.
+func (s *Submitter) Depth() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.q.depth
+}
+
+// Stats returns the metrics of the spool.
+func (s *Submitter) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return Stats{
+		Depth:    s.q.depth,
+		Bytes:    s.q.bytes(),
+		Dropped:  s.q.dropped,
+		Expired:  s.q.expired,
+		Rejected: s.rejected,
+		Replayed: s.replayed,
+	}
+}
+
+// Close closes the spool. Spooled results are kept on disk.
+func (s *Submitter) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.q.close()
+}
+
+func (s *Submitter) push(e *Entry) error {
+	s.mu.Lock()
+	err := s.q.push(e)
+	depth := s.q.depth
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to spool check result: %w", err)
+	}
+	s.notify(depth)
+	return nil
+}
+
+func (s *Submitter) notify(depth int) {
+	if s.onDepth != nil {
+		s.onDepth(depth)
+	}
+}
+
+// service returns the service the spooled result belongs to.
+func (e *Entry) service() *api.Service {
+	srv := &api.Service{HostName: e.Host}
+	srv.Name = e.Service
+	srv.LastCheckResult = e.Result
+	return srv
+}
+
+// isUnavailable reports whether the error indicates that the icinga API could not process
+// the result at the moment: the circuit breaker is open, no response was received, or the API
+// rate limited the request with a 429 or failed it as a whole with a 5xx. Icinga reports errors
+// of single objects as internal errors, which would fail again when replayed. Local errors,
+// e.g. of the credential provider, and failed certificate verifications would fail again as well.
+func isUnavailable(err error) bool {
+	if errors.Is(err, api.ErrCircuitOpen) {
+		return true
+	}
+	var intErr *api.IcingaInternalError
+	if errors.As(err, &intErr) {
+		return false
+	}
+	var icErr *api.IcingaError
+	if errors.As(err, &icErr) {
+		return icErr.Err == http.StatusTooManyRequests || icErr.Err >= http.StatusInternalServerError
+	}
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return false
+	}
+	var certErr *tls.CertificateVerificationError
+	return !errors.As(err, &certErr)
+}
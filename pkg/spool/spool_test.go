@@ -0,0 +1,462 @@
+package spool
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/puffitos/goicinga/pkg/api"
+)
+
+// errConnRefused is the error of the http.Client for an unreachable icinga API.
+var errConnRefused = &url.Error{
+	Op:  "Post",
+	URL: "https://icinga-server:5665/v1/actions/process-check-result",
+	Err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED},
+}
+
+// fakeActions records the submitted results and fails while err is set.
+type fakeActions struct {
+	mu        sync.Mutex
+	err       error
+	submitted []string
+	// before is called before every submission, if set
+	before func()
+}
+
+func (f *fakeActions) ProcessCheckResult(_ context.Context, srv *api.Service) (api.ActionResults, error) {
+	if f.before != nil {
+		f.before()
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	name := srv.HostName + "!" + srv.Name
+	f.submitted = append(f.submitted, fmt.Sprintf("%s:%s", name, srv.LastCheckResult.Output))
+	return api.ActionResults{{Code: 200, Name: name}}, nil
+}
+
+func (f *fakeActions) AcknowledgeProblem(context.Context, *api.AcknowledgeProblemRequest) (api.ActionResults, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeActions) RemoveAcknowledgement(context.Context, *api.RemoveAcknowledgementRequest) (api.ActionResults, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeActions) setErr(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+}
+
+func testResult(host, name, output string) *api.Service {
+	srv := &api.Service{HostName: host}
+	srv.Name = name
+	srv.LastCheckResult = api.CheckResult{ExitStatus: 2, Output: output, PerformanceData: []string{"load=1"}}
+	return srv
+}
+
+func newTestSubmitter(t *testing.T, actions api.Actions, config Config) *Submitter {
+	t.Helper()
+	if config.Dir == "" {
+		config.Dir = t.TempDir()
+	}
+	s, err := NewSubmitter(actions, &config, nil)
+	if err != nil {
+		t.Fatalf("NewSubmitter() error = %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func TestSubmitter_ProcessCheckResult(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantErr   bool
+		wantDepth int
+	}{
+		{
+			name: "submitted directly",
+		},
+		{
+			name:      "spooled on connection error",
+			err:       errConnRefused,
+			wantDepth: 1,
+		},
+		{
+			name:      "spooled on open circuit",
+			err:       api.ErrCircuitOpen,
+			wantDepth: 1,
+		},
+		{
+			name:      "spooled on unavailable API",
+			err:       &api.IcingaError{Err: 503, Status: "Service Unavailable"},
+			wantDepth: 1,
+		},
+		{
+			name:      "spooled on rate limited API",
+			err:       &api.IcingaError{Err: 429, Status: "Too Many Requests"},
+			wantDepth: 1,
+		},
+		{
+			name:    "credential error is returned",
+			err:     fmt.Errorf("failed to get credentials: %w", errors.New("vault sealed")),
+			wantErr: true,
+		},
+		{
+			name: "certificate error is returned",
+			err: &url.Error{Op: "Post", URL: "https://icinga-server:5665/v1/actions/process-check-result",
+				Err: &tls.CertificateVerificationError{Err: errors.New("certificate signed by unknown authority")}},
+			wantErr: true,
+		},
+		{
+			name:    "refused result is returned",
+			err:     &api.IcingaError{Err: 404, Status: "No objects found."},
+			wantErr: true,
+		},
+		{
+			name:    "object error is returned",
+			err:     &api.IcingaInternalError{Code: 500, Name: "test", Status: "failed"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeActions{err: tt.err}
+			s := newTestSubmitter(t, f, Config{})
+
+			_, err := s.ProcessCheckResult(context.Background(), testResult("h1", "s1", "out"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ProcessCheckResult() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := s.Depth(); got != tt.wantDepth {
+				t.Errorf("Depth() = %d, want %d", got, tt.wantDepth)
+			}
+		})
+	}
+}
+
+func TestSubmitter_Flush(t *testing.T) {
+	f := &fakeActions{err: errConnRefused}
+	var depths []int
+	s := newTestSubmitter(t, f, Config{OnDepthChange: func(d int) { depths = append(depths, d) }})
+
+	for i := 0; i < 3; i++ {
+		if _, err := s.ProcessCheckResult(context.Background(), testResult("h1", "s1", fmt.Sprint(i))); err != nil {
+			t.Fatalf("ProcessCheckResult() error = %v", err)
+		}
+	}
+	if err := s.Flush(context.Background()); err == nil {
+		t.Fatal("Flush() expected error while the API is unavailable")
+	}
+
+	// results must stay in order, even if the API recovers while results are spooled
+	f.setErr(nil)
+	if _, err := s.ProcessCheckResult(context.Background(), testResult("h1", "s1", "3")); err != nil {
+		t.Fatalf("ProcessCheckResult() error = %v", err)
+	}
+	if len(f.submitted) != 0 {
+		t.Fatalf("result submitted before the spooled ones: %v", f.submitted)
+	}
+
+	if err := s.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	want := []string{"h1!s1:0", "h1!s1:1", "h1!s1:2", "h1!s1:3"}
+	if !reflect.DeepEqual(f.submitted, want) {
+		t.Errorf("submitted = %v, want %v", f.submitted, want)
+	}
+	if want := []int{1, 2, 3, 4, 3, 2, 1, 0}; !reflect.DeepEqual(depths, want) {
+		t.Errorf("depths = %v, want %v", depths, want)
+	}
+	stats := s.Stats()
+	if stats.Depth != 0 || stats.Bytes != 0 || stats.Replayed != 4 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+
+	// the spool is used directly again once it is empty
+	if _, err := s.ProcessCheckResult(context.Background(), testResult("h1", "s1", "4")); err != nil {
+		t.Fatalf("ProcessCheckResult() error = %v", err)
+	}
+	if len(f.submitted) != 5 {
+		t.Errorf("submitted = %v, want 5 results", f.submitted)
+	}
+}
+
+func TestSubmitter_FlushDropsRefused(t *testing.T) {
+	f := &fakeActions{err: errConnRefused}
+	s := newTestSubmitter(t, f, Config{})
+	for i := 0; i < 2; i++ {
+		if _, err := s.ProcessCheckResult(context.Background(), testResult("h1", "s1", fmt.Sprint(i))); err != nil {
+			t.Fatalf("ProcessCheckResult() error = %v", err)
+		}
+	}
+
+	f.setErr(&api.IcingaError{Err: 404, Status: "No objects found."})
+	if err := s.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if stats := s.Stats(); stats.Depth != 0 || stats.Rejected != 2 {
+		t.Errorf("Stats() = %+v, want all results rejected", stats)
+	}
+}
+
+func TestSubmitter_FlushKeeps(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{
+			name: "rate limited",
+			err:  &api.IcingaError{Err: 429, Status: "Too Many Requests"},
+		},
+		{
+			name: "local error",
+			err:  fmt.Errorf("failed to get credentials: %w", errors.New("vault sealed")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeActions{err: errConnRefused}
+			s := newTestSubmitter(t, f, Config{})
+			if _, err := s.ProcessCheckResult(context.Background(), testResult("h1", "s1", "out")); err != nil {
+				t.Fatalf("ProcessCheckResult() error = %v", err)
+			}
+
+			f.setErr(tt.err)
+			if err := s.Flush(context.Background()); err == nil {
+				t.Fatalf("Flush() error = nil, want %v", tt.err)
+			}
+			if stats := s.Stats(); stats.Depth != 1 || stats.Rejected != 0 {
+				t.Errorf("Stats() = %+v, want the result kept", stats)
+			}
+		})
+	}
+}
+
+func TestSubmitter_TTL(t *testing.T) {
+	f := &fakeActions{err: errConnRefused}
+	s := newTestSubmitter(t, f, Config{TTL: 50 * time.Millisecond})
+
+	if _, err := s.ProcessCheckResult(context.Background(), testResult("h1", "s1", "old")); err != nil {
+		t.Fatalf("ProcessCheckResult() error = %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, err := s.ProcessCheckResult(context.Background(), testResult("h1", "s1", "new")); err != nil {
+		t.Fatalf("ProcessCheckResult() error = %v", err)
+	}
+
+	f.setErr(nil)
+	if err := s.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if want := []string{"h1!s1:new"}; !reflect.DeepEqual(f.submitted, want) {
+		t.Errorf("submitted = %v, want %v", f.submitted, want)
+	}
+	if got := s.Stats().Expired; got != 1 {
+		t.Errorf("Stats().Expired = %d, want 1", got)
+	}
+}
+
+func TestSubmitter_ResultTTL(t *testing.T) {
+	f := &fakeActions{err: errConnRefused}
+	s := newTestSubmitter(t, f, Config{})
+
+	stale := testResult("h1", "s1", "stale")
+	stale.LastCheckResult.TTL = api.IcingaDuration(50 * time.Millisecond)
+	fresh := testResult("h1", "s2", "fresh")
+	fresh.LastCheckResult.TTL = api.IcingaDuration(time.Hour)
+	for _, srv := range []*api.Service{stale, fresh} {
+		if _, err := s.ProcessCheckResult(context.Background(), srv); err != nil {
+			t.Fatalf("ProcessCheckResult() error = %v", err)
+		}
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	f.setErr(nil)
+	if err := s.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if want := []string{"h1!s2:fresh"}; !reflect.DeepEqual(f.submitted, want) {
+		t.Errorf("submitted = %v, want %v", f.submitted, want)
+	}
+	if got := s.Stats().Expired; got != 1 {
+		t.Errorf("Stats().Expired = %d, want 1", got)
+	}
+}
+
+func TestSubmitter_MaxBytes(t *testing.T) {
+	f := &fakeActions{err: errConnRefused}
+	dir := t.TempDir()
+	s := newTestSubmitter(t, f, Config{Dir: dir, MaxBytes: 1024})
+
+	for i := 0; i < 50; i++ {
+		if _, err := s.ProcessCheckResult(context.Background(), testResult("h1", "s1", fmt.Sprint(i))); err != nil {
+			t.Fatalf("ProcessCheckResult() error = %v", err)
+		}
+		fi, err := s.q.f.Stat()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Size() > 1024 {
+			t.Fatalf("spool size %d exceeds MaxBytes", fi.Size())
+		}
+	}
+
+	stats := s.Stats()
+	if stats.Dropped == 0 || stats.Depth+int(stats.Dropped) != 50 {
+		t.Errorf("Stats() = %+v, want the oldest results dropped", stats)
+	}
+
+	f.setErr(nil)
+	if err := s.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if got := f.submitted[len(f.submitted)-1]; got != "h1!s1:49" {
+		t.Errorf("last submitted = %s, want the newest result", got)
+	}
+
+	big := testResult("h1", "s1", string(make([]byte, 2048)))
+	f.setErr(errConnRefused)
+	if _, err := s.ProcessCheckResult(context.Background(), big); err == nil {
+		t.Error("ProcessCheckResult() expected error for a result larger than MaxBytes")
+	}
+}
+
+func TestSubmitter_Reopen(t *testing.T) {
+	f := &fakeActions{err: errConnRefused}
+	dir := t.TempDir()
+	s := newTestSubmitter(t, f, Config{Dir: dir})
+	for i := 0; i < 3; i++ {
+		if _, err := s.ProcessCheckResult(context.Background(), testResult("h1", "s1", fmt.Sprint(i))); err != nil {
+			t.Fatalf("ProcessCheckResult() error = %v", err)
+		}
+	}
+
+	// submit the first result only, then simulate a crash while writing another one
+	f.setErr(nil)
+	s.mu.Lock()
+	_, err := s.q.peek()
+	if err == nil {
+		err = s.q.ack()
+	}
+	logPath := s.q.f.Name()
+	s.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	lf, err := os.OpenFile(logPath, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lf.WriteString(`{"host":"h1","serv`); err != nil {
+		t.Fatal(err)
+	}
+	_ = lf.Close()
+
+	s = newTestSubmitter(t, f, Config{Dir: dir})
+	if got := s.Depth(); got != 2 {
+		t.Fatalf("Depth() = %d after reopening, want 2", got)
+	}
+	if err := s.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if want := []string{"h1!s1:1", "h1!s1:2"}; !reflect.DeepEqual(f.submitted, want) {
+		t.Errorf("submitted = %v, want %v", f.submitted, want)
+	}
+}
+
+func TestSubmitter_FlushWithCompaction(t *testing.T) {
+	f := &fakeActions{err: errConnRefused}
+	s := newTestSubmitter(t, f, Config{MaxBytes: 1024})
+	for i := 0; i < 3; i++ {
+		if _, err := s.ProcessCheckResult(context.Background(), testResult("h1", "s1", fmt.Sprint(i))); err != nil {
+			t.Fatalf("ProcessCheckResult() error = %v", err)
+		}
+	}
+
+	// new results compact the spool while the first one is replayed
+	var once sync.Once
+	f.before = func() {
+		once.Do(func() {
+			for i := 3; i < 10; i++ {
+				if _, err := s.ProcessCheckResult(context.Background(), testResult("h1", "s1", fmt.Sprint(i))); err != nil {
+					t.Errorf("ProcessCheckResult() error = %v", err)
+				}
+			}
+		})
+	}
+	f.setErr(nil)
+	if err := s.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	if len(f.submitted) < 2 || f.submitted[0] != "h1!s1:0" || f.submitted[len(f.submitted)-1] != "h1!s1:9" {
+		t.Errorf("submitted = %v, want the replayed result first and the newest last", f.submitted)
+	}
+	for i, r := range f.submitted[1:] {
+		if r == f.submitted[i] {
+			t.Errorf("submitted = %v, want no result twice", f.submitted)
+		}
+	}
+	stats := s.Stats()
+	if stats.Depth != 0 || stats.Bytes != 0 || int(stats.Replayed+stats.Dropped) != 10 {
+		t.Errorf("Stats() = %+v, want all results replayed or dropped", stats)
+	}
+}
+
+func TestSubmitter_ReopenAfterCompaction(t *testing.T) {
+	f := &fakeActions{err: errConnRefused}
+	dir := t.TempDir()
+	s := newTestSubmitter(t, f, Config{Dir: dir, MaxBytes: 1024})
+	for i := 0; i < 10; i++ {
+		if _, err := s.ProcessCheckResult(context.Background(), testResult("h1", "s1", fmt.Sprint(i))); err != nil {
+			t.Fatalf("ProcessCheckResult() error = %v", err)
+		}
+	}
+	depth := s.Depth()
+	if s.Stats().Dropped == 0 {
+		t.Fatal("spool was not compacted")
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// a crash during the next compaction leaves a log file of the next generation behind
+	stale := filepath.Join(dir, fmt.Sprintf(logFilePattern, s.q.gen+1))
+	if err := os.WriteFile(stale, []byte(`{"host":"h1","service":"s1"}`+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s = newTestSubmitter(t, f, Config{Dir: dir, MaxBytes: 1024})
+	if got := s.Depth(); got != depth {
+		t.Fatalf("Depth() = %d after reopening, want %d", got, depth)
+	}
+	if _, err := os.Stat(stale); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("stale log file was not removed: %v", err)
+	}
+	f.setErr(nil)
+	if err := s.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if len(f.submitted) != depth || f.submitted[depth-1] != "h1!s1:9" {
+		t.Errorf("submitted = %v, want the %d newest results", f.submitted, depth)
+	}
+}
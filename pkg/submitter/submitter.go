@@ -0,0 +1,202 @@
+// Package submitter submits passive check results received on a channel with bounded concurrency,
+// e.g. from many producers through api.Actions or a spool.Submitter.
+package submitter
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/puffitos/goicinga/pkg/api"
+)
+
+const (
+	defaultSubmitWorkers  = 10
+	defaultCoalesceWindow = time.Second
+	defaultDrainTimeout   = 30 * time.Second
+)
+
+// Processor processes check results, e.g. api.Actions or a spool.Submitter.
+type Processor interface {
+	ProcessCheckResult(ctx context.Context, srv *api.Service) (api.ActionResults, error)
+}
+
+// Config configures a Submitter.
+type Config struct {
+	// Workers is the maximum number of concurrent submissions. Defaults to 10.
+	Workers int
+	// CoalesceWindow is the interval pending results are submitted in. Results for the same
+	// service within a window are coalesced, only the latest one is submitted. Defaults to 1s.
+	CoalesceWindow time.Duration
+	// DrainTimeout bounds the time to submit the pending results after the context
+	// passed to Run is canceled. Defaults to 30s.
+	DrainTimeout time.Duration
+	// OnFailure is called for every result which could not be submitted.
+	// It is called concurrently by the workers.
+	OnFailure func(srv *api.Service, err error)
+}
+
+// Submitter submits passive check results received on a channel through a Processor,
+// with bounded concurrency. Results for the same service are submitted in order.
+type Submitter struct {
+	processor Processor
+	cfg       Config
+
+	mu sync.Mutex
+	// inFlight holds the services whose result is being submitted
+	inFlight map[string]bool
+}
+
+// New creates a Submitter which submits through the given processor.
+func New(processor Processor, config Config) *Submitter {
+	if config.Workers <= 0 {
+		config.Workers = defaultSubmitWorkers
+	}
+	if config.CoalesceWindow <= 0 {
+		config.CoalesceWindow = defaultCoalesceWindow
+	}
+	if config.DrainTimeout <= 0 {
+		config.DrainTimeout = defaultDrainTimeout
+	}
+	return &Submitter{
+		processor: processor,
+		cfg:       config,
+		inFlight:  map[string]bool{},
+	}
+}
+
+// pendingResults holds the latest result per service, in the order the services were first seen.
+type pendingResults struct {
+	results map[string]*api.Service
+	keys    []string
+}
+
+func (p *pendingResults) add(srv *api.Service) {
+	k := checkResultKey(srv)
+	if _, ok := p.results[k]; !ok {
+		p.keys = append(p.keys, k)
+	}
+	p.results[k] = srv
+}
+
+func (p *pendingResults) len() int {
+	return len(p.keys)
+}
+
+// Run submits the results received on the channel until the channel is closed or the context
+// is canceled. Either way, the pending results, including the ones buffered in the channel,
+// are submitted before Run returns. Returns the error of the context if it was canceled.
+func (s *Submitter) Run(ctx context.Context, results <-chan *api.Service) error {
+	// submissions are not canceled with ctx, so pending results can be drained
+	submitCtx, cancelSubmit := context.WithCancel(context.Background())
+	defer cancelSubmit()
+
+	jobs := make(chan *api.Service)
+	var wg sync.WaitGroup
+	for i := 0; i < s.cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for srv := range jobs {
+				s.submit(submitCtx, srv)
+			}
+		}()
+	}
+
+	pending := &pendingResults{results: map[string]*api.Service{}}
+	ticker := time.NewTicker(s.cfg.CoalesceWindow)
+	defer ticker.Stop()
+
+	var err error
+loop:
+	for {
+		select {
+		case srv, ok := <-results:
+			if !ok {
+				break loop
+			}
+			if srv != nil {
+				pending.add(srv)
+			}
+		case <-ticker.C:
+			s.dispatch(pending, jobs)
+		case <-ctx.Done():
+			err = ctx.Err()
+			s.drainChannel(results, pending)
+			break loop
+		}
+	}
+
+	timer := time.AfterFunc(s.cfg.DrainTimeout, cancelSubmit)
+	defer timer.Stop()
+
+	s.dispatch(pending, jobs)
+	close(jobs)
+	wg.Wait()
+	// results of services which were in flight during the last dispatch
+	for _, k := range pending.keys {
+		s.submit(submitCtx, pending.results[k])
+	}
+	return err
+}
+
+// drainChannel moves the results buffered in the channel to the pending results.
+func (s *Submitter) drainChannel(results <-chan *api.Service, pending *pendingResults) {
+	for {
+		select {
+		case srv, ok := <-results:
+			if !ok {
+				return
+			}
+			if srv != nil {
+				pending.add(srv)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// dispatch hands the pending results to the workers. Results of services which are still
+// in flight stay pending, so the results of a service are never submitted concurrently.
+func (s *Submitter) dispatch(pending *pendingResults, jobs chan<- *api.Service) {
+	if pending.len() == 0 {
+		return
+	}
+
+	var remaining []string
+	for _, k := range pending.keys {
+		s.mu.Lock()
+		busy := s.inFlight[k]
+		if !busy {
+			s.inFlight[k] = true
+		}
+		s.mu.Unlock()
+
+		if busy {
+			remaining = append(remaining, k)
+			continue
+		}
+		jobs <- pending.results[k]
+		delete(pending.results, k)
+	}
+	pending.keys = remaining
+}
+
+// submit submits a single result and reports a failure.
+func (s *Submitter) submit(ctx context.Context, srv *api.Service) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.inFlight, checkResultKey(srv))
+		s.mu.Unlock()
+	}()
+
+	if _, err := s.processor.ProcessCheckResult(ctx, srv); err != nil && s.cfg.OnFailure != nil {
+		s.cfg.OnFailure(srv, err)
+	}
+}
+
+// checkResultKey identifies the service a result belongs to.
+func checkResultKey(srv *api.Service) string {
+	return srv.HostName + "!" + srv.Name
+}
@@ -0,0 +1,144 @@
+package submitter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/puffitos/goicinga/pkg/api"
+	"github.com/puffitos/goicinga/pkg/spool"
+)
+
+// the results are submitted directly or through the spool
+var (
+	_ Processor = api.Actions(nil)
+	_ Processor = (*spool.Submitter)(nil)
+)
+
+// recordingProcessor records the submitted results and fails for the services in fail.
+type recordingProcessor struct {
+	mu        sync.Mutex
+	delay     time.Duration
+	fail      map[string]bool
+	submitted []string
+	inFlight  int
+	maxFlight int
+}
+
+func (a *recordingProcessor) ProcessCheckResult(ctx context.Context, srv *api.Service) (api.ActionResults, error) {
+	a.mu.Lock()
+	a.inFlight++
+	if a.inFlight > a.maxFlight {
+		a.maxFlight = a.inFlight
+	}
+	a.mu.Unlock()
+
+	time.Sleep(a.delay)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.inFlight--
+	if a.fail[srv.Name] {
+		return nil, errors.New("failed")
+	}
+	a.submitted = append(a.submitted, fmt.Sprintf("%s:%s", srv.Name, srv.LastCheckResult.Output))
+	return api.ActionResults{{Code: 200, Name: checkResultKey(srv)}}, nil
+}
+
+func testCheckResult(name, output string) *api.Service {
+	srv := &api.Service{HostName: "host"}
+	srv.Name = name
+	srv.LastCheckResult.Output = output
+	return srv
+}
+
+func TestSubmitter_Coalesce(t *testing.T) {
+	a := &recordingProcessor{fail: map[string]bool{"broken": true}}
+	var failed []string
+	var mu sync.Mutex
+	s := New(a, Config{
+		CoalesceWindow: time.Hour,
+		OnFailure: func(srv *api.Service, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			failed = append(failed, srv.Name)
+		},
+	})
+
+	results := make(chan *api.Service, 10)
+	for i := 0; i < 3; i++ {
+		results <- testCheckResult("a", fmt.Sprint(i))
+		results <- testCheckResult("b", fmt.Sprint(i))
+	}
+	results <- testCheckResult("broken", "0")
+	close(results)
+
+	if err := s.Run(context.Background(), results); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	want := map[string]bool{"a:2": true, "b:2": true}
+	if len(a.submitted) != len(want) {
+		t.Fatalf("submitted = %v, want the latest result per service", a.submitted)
+	}
+	for _, s := range a.submitted {
+		if !want[s] {
+			t.Errorf("unexpected submission %s", s)
+		}
+	}
+	if len(failed) != 1 || failed[0] != "broken" {
+		t.Errorf("failed = %v, want [broken]", failed)
+	}
+}
+
+func TestSubmitter_Workers(t *testing.T) {
+	a := &recordingProcessor{delay: 20 * time.Millisecond}
+	s := New(a, Config{Workers: 3, CoalesceWindow: 10 * time.Millisecond})
+
+	results := make(chan *api.Service)
+	done := make(chan error)
+	go func() { done <- s.Run(context.Background(), results) }()
+	for i := 0; i < 20; i++ {
+		results <- testCheckResult(fmt.Sprint(i), "0")
+	}
+	close(results)
+	if err := <-done; err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if len(a.submitted) != 20 {
+		t.Errorf("submitted %d results, want 20", len(a.submitted))
+	}
+	if a.maxFlight > 3 {
+		t.Errorf("%d concurrent submissions, want at most 3", a.maxFlight)
+	}
+}
+
+func TestSubmitter_Drain(t *testing.T) {
+	a := &recordingProcessor{}
+	s := New(a, Config{CoalesceWindow: time.Hour})
+
+	results := make(chan *api.Service, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx, results) }()
+
+	results <- testCheckResult("a", "0")
+	results <- testCheckResult("b", "0")
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Run() error = %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Run() did not return after the context was canceled")
+	}
+	if len(a.submitted) != 2 {
+		t.Errorf("submitted = %v, want the pending results drained", a.submitted)
+	}
+}